@@ -0,0 +1,206 @@
+package g8
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	newrelic "github.com/newrelic/go-agent"
+	"github.com/newrelic/go-agent/_integrations/nrlambda"
+	"github.com/rs/zerolog"
+)
+
+// ALBContext is the context for requests from an Application Load Balancer target group. When multi-value headers
+// are enabled on the target group the request headers are in MultiValueHeaders, and response headers set on the
+// context are returned in MultiValueHeaders as the load balancer expects.
+type ALBContext struct {
+	Context       context.Context
+	Request       events.ALBTargetGroupRequest
+	Response      events.ALBTargetGroupResponse
+	Logger        zerolog.Logger
+	NewRelicTx    newrelic.Transaction
+	CorrelationID string
+	validator     Validator
+	errorRenderer ErrorRenderer
+	maxBodySize   int64
+}
+
+type ALBHandlerFunc func(c *ALBContext) error
+
+func ALBHandler(
+	h ALBHandlerFunc,
+	conf HandlerConfig,
+) func(context.Context, events.ALBTargetGroupRequest) (any, error) {
+	h = chainMiddleware(h, conf.ALBMiddleware)
+	return func(ctx context.Context, r events.ALBTargetGroupRequest) (any, error) {
+		c := &ALBContext{
+			Context:       ctx,
+			Request:       r,
+			NewRelicTx:    newrelic.FromContext(ctx),
+			validator:     conf.Validator,
+			errorRenderer: conf.ErrorRenderer,
+			maxBodySize:   conf.MaxBodySize,
+		}
+		c.CorrelationID = c.GetHeader(headerCorrelationID)
+		if c.CorrelationID == "" {
+			c.CorrelationID = uuid.New().String()
+		}
+		c.Context = ContextWithCorrelationID(ctx, c.CorrelationID)
+
+		c.Logger = configureLogger(conf).
+			Str("route", r.Path).
+			Str("correlation_id", c.CorrelationID).
+			Logger()
+
+		c.SetHeader(headerCorrelationID, c.CorrelationID)
+		c.SetHeader(headerBuildVersion, conf.BuildVersion)
+
+		c.AddNewRelicAttribute("functionName", conf.FunctionName)
+		c.AddNewRelicAttribute("route", r.Path)
+		c.AddNewRelicAttribute("targetGroupArn", r.RequestContext.ELB.TargetGroupArn)
+		c.AddNewRelicAttribute("correlationID", c.CorrelationID)
+		c.AddNewRelicAttribute("buildVersion", conf.BuildVersion)
+
+		err := callHandler(c.NewRelicTx, func() error { return h(c) })
+		if err != nil {
+			c.handleError(err)
+		}
+
+		return c.response(), nil
+	}
+}
+
+func ALBHandlerWithNewRelic(h ALBHandlerFunc, conf HandlerConfig) lambda.Handler {
+	return nrlambda.Wrap(ALBHandler(h, conf), conf.NewRelicApp)
+}
+
+func (c *ALBContext) Bind(v interface{}) error {
+	b, err := c.Body()
+	if err != nil {
+		return err
+	}
+
+	return bindJSON(b, v, c.validator)
+}
+
+// Body returns the raw request body, decoding it when the load balancer has base64 encoded it and decompressing
+// it according to the Content-Encoding header. ErrRequestBodyTooLarge is returned when the body is larger than the
+// configured MaxBodySize.
+func (c *ALBContext) Body() ([]byte, error) {
+	b, err := decodeBody(c.Request.Body, c.Request.IsBase64Encoded)
+	if err != nil {
+		return nil, err
+	}
+	return decompressBody(b, c.GetHeader("Content-Encoding"), c.maxBodySize)
+}
+
+func (c *ALBContext) JSON(statusCode int, body interface{}) error {
+	var b []byte
+	var err error
+	if body != nil {
+		b, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		c.SetHeader("Content-Type", contentTypeJSON)
+	}
+	c.Response.StatusCode = statusCode
+	c.Response.Body = string(b)
+	c.Response.IsBase64Encoded = false
+	return nil
+}
+
+// SetHeader sets a response header, replacing any existing values
+func (c *ALBContext) SetHeader(key, value string) {
+	if c.Response.MultiValueHeaders == nil {
+		c.Response.MultiValueHeaders = make(map[string][]string)
+	}
+	c.Response.MultiValueHeaders[key] = []string{value}
+}
+
+// AddHeader adds a value to a response header. Only the last value of each header is returned when multi-value
+// headers aren't enabled on the target group.
+func (c *ALBContext) AddHeader(key, value string) {
+	if c.Response.MultiValueHeaders == nil {
+		c.Response.MultiValueHeaders = make(map[string][]string)
+	}
+	c.Response.MultiValueHeaders[key] = append(c.Response.MultiValueHeaders[key], value)
+}
+
+func (c *ALBContext) AddNewRelicAttribute(key string, val interface{}) {
+	if c.NewRelicTx == nil {
+		return
+	}
+	if err := c.NewRelicTx.AddAttribute(key, val); err != nil {
+		c.Logger.Error().Msgf("failed to add attr '%s' to new relic tx: %+v", key, err)
+	}
+}
+
+func (c *ALBContext) handleError(err error) {
+	statusCode, contentType, b := renderHandlerError(c.Logger, c.errorRenderer, err, c.CorrelationID)
+	c.SetHeader("Content-Type", contentType)
+	c.Response.StatusCode = statusCode
+	c.Response.Body = string(b)
+	c.Response.IsBase64Encoded = false
+}
+
+// GetCookie retrieves the cookie with the given name
+func (c *ALBContext) GetCookie(name string) (http.Cookie, bool) {
+	return lookupCookie([]string{c.GetHeader("cookie")}, name)
+}
+
+// GetHeader retrieves the header value by name in a case insensitive manner, from either the single or multi-value
+// headers depending on which are enabled on the target group
+func (c *ALBContext) GetHeader(name string) string {
+	if c.multiValueHeaders() {
+		return http.Header(canonicalHeaders(c.Request.MultiValueHeaders)).Get(name)
+	}
+	return lookupHeader(c.Request.Headers, name)
+}
+
+func (c *ALBContext) multiValueHeaders() bool {
+	return c.Request.MultiValueHeaders != nil
+}
+
+// response returns the response with the headers in the format expected by the target group
+func (c *ALBContext) response() events.ALBTargetGroupResponse {
+	r := c.Response
+	r.StatusDescription = fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode))
+
+	headers := r.MultiValueHeaders
+	for k, v := range r.Headers {
+		if headers == nil {
+			headers = make(map[string][]string)
+		}
+		headers[k] = []string{v}
+	}
+
+	if c.multiValueHeaders() {
+		r.Headers = nil
+		r.MultiValueHeaders = headers
+		return r
+	}
+
+	r.MultiValueHeaders = nil
+	r.Headers = make(map[string]string, len(headers))
+	for k, v := range headers {
+		if len(v) > 0 {
+			r.Headers[k] = v[len(v)-1]
+		}
+	}
+	return r
+}
+
+func canonicalHeaders(headers map[string][]string) map[string][]string {
+	canonical := make(map[string][]string, len(headers))
+	for k, v := range headers {
+		key := http.CanonicalHeaderKey(k)
+		canonical[key] = append(canonical[key], v...)
+	}
+	return canonical
+}
@@ -0,0 +1,181 @@
+package g8_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JSainsburyPLC/g8"
+)
+
+func TestALBHandler_SingleValueHeaders(t *testing.T) {
+	h := func(c *g8.ALBContext) error {
+		var b body
+		if err := c.Bind(&b); err != nil {
+			return err
+		}
+		cookie, ok := c.GetCookie("session")
+		assert.True(t, ok)
+		assert.Equal(t, "abc", cookie.Value)
+
+		c.AddHeader("Set-Cookie", "theme=dark")
+		c.AddHeader("Set-Cookie", "lang=en")
+		return c.JSON(http.StatusCreated, b)
+	}
+
+	lh := g8.ALBHandler(h, g8.HandlerConfig{BuildVersion: "1.0.0"})
+
+	res, err := lh(context.Background(), events.ALBTargetGroupRequest{
+		HTTPMethod: http.MethodPost,
+		Path:       "/items",
+		Headers: map[string]string{
+			"content-type":   "application/json",
+			"correlation-id": "abcdef",
+			"cookie":         "session=abc",
+		},
+		Body: `{"name":"one","status":"ok"}`,
+	})
+
+	assert.Nil(t, err)
+	r := res.(events.ALBTargetGroupResponse)
+	assert.Equal(t, http.StatusCreated, r.StatusCode)
+	assert.Equal(t, "201 Created", r.StatusDescription)
+	assert.JSONEq(t, `{"name":"one","status":"ok"}`, r.Body)
+	assert.Nil(t, r.MultiValueHeaders)
+	assert.Equal(t, map[string]string{
+		"Content-Type":   "application/json",
+		"Correlation-Id": "abcdef",
+		"Build-Version":  "1.0.0",
+		"Set-Cookie":     "lang=en",
+	}, r.Headers)
+}
+
+func TestALBHandler_MultiValueHeaders(t *testing.T) {
+	h := func(c *g8.ALBContext) error {
+		assert.Equal(t, "tenant-1", c.GetHeader("X-Tenant-Id"))
+		cookie, ok := c.GetCookie("session")
+		assert.True(t, ok)
+		assert.Equal(t, "abc", cookie.Value)
+
+		c.AddHeader("Set-Cookie", "theme=dark")
+		c.AddHeader("Set-Cookie", "lang=en")
+		return c.JSON(http.StatusOK, body{Name: "one"})
+	}
+
+	lh := g8.ALBHandler(h, g8.HandlerConfig{BuildVersion: "1.0.0"})
+
+	res, err := lh(context.Background(), events.ALBTargetGroupRequest{
+		HTTPMethod: http.MethodGet,
+		Path:       "/items",
+		MultiValueHeaders: map[string][]string{
+			"x-tenant-id":    {"tenant-1"},
+			"correlation-id": {"abcdef"},
+			"cookie":         {"session=abc"},
+		},
+	})
+
+	assert.Nil(t, err)
+	r := res.(events.ALBTargetGroupResponse)
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+	assert.Equal(t, "200 OK", r.StatusDescription)
+	assert.Nil(t, r.Headers)
+	assert.Equal(t, map[string][]string{
+		"Content-Type":   {"application/json"},
+		"Correlation-Id": {"abcdef"},
+		"Build-Version":  {"1.0.0"},
+		"Set-Cookie":     {"theme=dark", "lang=en"},
+	}, r.MultiValueHeaders)
+}
+
+func TestALBHandler_ErrorResponse(t *testing.T) {
+	testCases := map[string]struct {
+		request         events.ALBTargetGroupRequest
+		err             error
+		expectedStatus  int
+		expectedBody    string
+		expectedLog     string
+		expectedHeaders func(r events.ALBTargetGroupResponse) string
+	}{
+		"g8 error single value headers": {
+			request:        events.ALBTargetGroupRequest{Headers: map[string]string{}},
+			err:            g8.ErrNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"code":"NOT_FOUND","detail":"Not found"}`,
+			expectedHeaders: func(r events.ALBTargetGroupResponse) string {
+				return r.Headers["Content-Type"]
+			},
+		},
+		"unhandled error multi value headers": {
+			request:        events.ALBTargetGroupRequest{MultiValueHeaders: map[string][]string{}},
+			err:            errors.New("some error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"code":"INTERNAL_SERVER_ERROR","detail":"Internal server error"}`,
+			expectedLog:    "Unhandled error",
+			expectedHeaders: func(r events.ALBTargetGroupResponse) string {
+				return r.MultiValueHeaders["Content-Type"][0]
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var logBuf bytes.Buffer
+			lh := g8.ALBHandler(func(c *g8.ALBContext) error {
+				return tc.err
+			}, g8.HandlerConfig{Logger: zerolog.New(&logBuf)})
+
+			res, err := lh(context.Background(), tc.request)
+
+			assert.Nil(t, err)
+			r := res.(events.ALBTargetGroupResponse)
+			assert.Equal(t, tc.expectedStatus, r.StatusCode)
+			assert.JSONEq(t, tc.expectedBody, r.Body)
+			assert.Equal(t, "application/json", tc.expectedHeaders(r))
+			if tc.expectedLog != "" {
+				assert.True(t, containsLogMessage(logBuf.String(), tc.expectedLog))
+			}
+		})
+	}
+}
+
+func TestALBContext_Bind(t *testing.T) {
+	testCases := map[string]struct {
+		request      events.ALBTargetGroupRequest
+		expectedBody body
+		expectedErr  error
+	}{
+		"success": {
+			request:      events.ALBTargetGroupRequest{Body: `{"name":"one","status":"ok"}`},
+			expectedBody: body{Name: "one", Status: "ok"},
+		},
+		"base64 encoded": {
+			request: events.ALBTargetGroupRequest{
+				Body:            `eyJuYW1lIjoib25lIiwic3RhdHVzIjoib2sifQ==`,
+				IsBase64Encoded: true,
+			},
+			expectedBody: body{Name: "one", Status: "ok"},
+		},
+		"validation error": {
+			request:      events.ALBTargetGroupRequest{Body: `{"name":"one"}`},
+			expectedBody: body{Name: "one"},
+			expectedErr:  errors.New("status empty"),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c := &g8.ALBContext{Request: tc.request}
+			var b body
+			err := c.Bind(&b)
+
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedBody, b)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	newrelic "github.com/newrelic/go-agent"
 	"github.com/newrelic/go-agent/_integrations/nrlambda"
+	"github.com/rotisserie/eris"
 	"github.com/rs/zerolog"
 )
 
@@ -30,7 +31,7 @@ func APIGatewayCustomAuthorizerHandler(
 	h APIGatewayCustomAuthorizerHandlerFunc,
 	conf HandlerConfig,
 ) func(context.Context, events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
-
+	h = chainMiddleware(h, conf.APIGatewayCustomAuthorizerMiddleware)
 	return func(ctx context.Context, r events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
 		if len(r.MethodArn) == 0 {
 			return events.APIGatewayCustomAuthorizerResponse{}, errors.New("MethodArn is not set")
@@ -48,7 +49,7 @@ func APIGatewayCustomAuthorizerHandler(
 			Logger()
 
 		c := &APIGatewayCustomAuthorizerContext{
-			Context:                    ctx,
+			Context:                    ContextWithCorrelationID(ctx, correlationID),
 			Request:                    r,
 			Response:                   NewAuthorizerResponse(),
 			Logger:                     logger,
@@ -58,8 +59,12 @@ func APIGatewayCustomAuthorizerHandler(
 			hasAtLeastOneAllowedMethod: false,
 		}
 
-		if err := h(c); err != nil {
-			logger.Err(err).Msg("Error while calling user-defined function")
+		if err := callHandler(c.NewRelicTx, func() error { return h(c) }); err != nil {
+			logger.Error().
+				Fields(map[string]interface{}{
+					"error": eris.ToJSON(err, true),
+				}).
+				Msg("Error while calling user-defined function")
 			return events.APIGatewayCustomAuthorizerResponse{}, err
 		}
 
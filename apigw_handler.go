@@ -1,12 +1,9 @@
 package g8
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -23,6 +20,9 @@ type APIGatewayProxyContext struct {
 	Logger        zerolog.Logger
 	NewRelicTx    newrelic.Transaction
 	CorrelationID string
+	validator     Validator
+	errorRenderer ErrorRenderer
+	maxBodySize   int64
 }
 
 type APIGatewayProxyHandlerFunc func(c *APIGatewayProxyContext) error
@@ -31,6 +31,7 @@ func APIGatewayProxyHandler(
 	h APIGatewayProxyHandlerFunc,
 	conf HandlerConfig,
 ) func(context.Context, events.APIGatewayProxyRequest) (any, error) {
+	h = chainMiddleware(h, conf.APIGatewayProxyMiddleware)
 	return func(ctx context.Context, r events.APIGatewayProxyRequest) (any, error) {
 		correlationID := getCorrelationIDAPIGW(r.Headers)
 
@@ -40,11 +41,14 @@ func APIGatewayProxyHandler(
 			Logger()
 
 		c := &APIGatewayProxyContext{
-			Context:       ctx,
+			Context:       ContextWithCorrelationID(ctx, correlationID),
 			Request:       r,
 			Logger:        logger,
 			NewRelicTx:    newrelic.FromContext(ctx),
 			CorrelationID: correlationID,
+			validator:     conf.Validator,
+			errorRenderer: conf.ErrorRenderer,
+			maxBodySize:   conf.MaxBodySize,
 		}
 
 		if c.Response.Headers == nil {
@@ -58,10 +62,22 @@ func APIGatewayProxyHandler(
 		c.AddNewRelicAttribute("correlationID", correlationID)
 		c.AddNewRelicAttribute("buildVersion", conf.BuildVersion)
 
-		err := h(c)
+		origin := c.GetHeader("Origin")
+		if conf.CORS.enabled() && isPreflightRequest(r.HTTPMethod, origin, c.GetHeader("Access-Control-Request-Method")) {
+			c.Response.StatusCode = http.StatusNoContent
+			headers := conf.CORS.preflightHeaders(origin, c.GetHeader("Access-Control-Request-Headers"))
+			applyCORSHeaders(&c.Response, conf.CORS, headers)
+			return c.Response, nil
+		}
+
+		err := callHandler(c.NewRelicTx, func() error { return h(c) })
 		if err != nil {
 			c.handleError(err)
-			return c.Response, nil
+		}
+		applyCORSHeaders(&c.Response, conf.CORS, conf.CORS.responseHeaders(origin))
+
+		if err := compressResponse(&c.Response, c.GetHeader("Accept-Encoding"), conf.Compression); err != nil {
+			c.Logger.Error().Msgf("failed to compress response: %+v", err)
 		}
 
 		return c.Response, nil
@@ -73,18 +89,23 @@ func APIGatewayProxyHandlerWithNewRelic(h APIGatewayProxyHandlerFunc, conf Handl
 }
 
 func (c *APIGatewayProxyContext) Bind(v interface{}) error {
-	if err := json.Unmarshal([]byte(c.Request.Body), v); err != nil {
-		return ErrInvalidBody
+	b, err := c.Body()
+	if err != nil {
+		return err
 	}
 
-	if validatable, ok := v.(Validatable); ok {
-		err := validatable.Validate()
-		if err != nil {
-			return err
-		}
-	}
+	return bindJSON(b, v, c.validator)
+}
 
-	return nil
+// Body returns the raw request body, decoding it when API Gateway has base64 encoded it, e.g. for binary media
+// types, and decompressing it according to the Content-Encoding header. ErrRequestBodyTooLarge is returned when
+// the body is larger than the configured MaxBodySize.
+func (c *APIGatewayProxyContext) Body() ([]byte, error) {
+	b, err := decodeBody(c.Request.Body, c.Request.IsBase64Encoded)
+	if err != nil {
+		return nil, err
+	}
+	return decompressBody(b, c.GetHeader("Content-Encoding"), c.maxBodySize)
 }
 
 func (c *APIGatewayProxyContext) JSON(statusCode int, body interface{}) error {
@@ -96,13 +117,11 @@ func (c *APIGatewayProxyContext) JSON(statusCode int, body interface{}) error {
 			return err
 		}
 
-		if c.Response.Headers == nil {
-			c.Response.Headers = make(map[string]string)
-		}
-		c.Response.Headers["Content-Type"] = "application/json"
+		c.setHeader("Content-Type", contentTypeJSON)
 	}
 	c.Response.StatusCode = statusCode
 	c.Response.Body = string(b)
+	c.Response.IsBase64Encoded = false
 	return nil
 }
 
@@ -116,37 +135,13 @@ func (c *APIGatewayProxyContext) AddNewRelicAttribute(key string, val interface{
 }
 
 func (c *APIGatewayProxyContext) handleError(err error) {
-	var newErr Err
-	switch err := err.(type) {
-	case Err:
-		newErr = err
-	default:
-		newErr = ErrInternalServer
-		logUnhandledError(c.Logger, err)
-	}
-	_ = c.JSON(newErr.Status, newErr)
+	statusCode, contentType, b := renderHandlerError(c.Logger, c.errorRenderer, err, c.CorrelationID)
+	c.write(statusCode, contentType, string(b))
 }
 
 // GetCookie retrieves the cookie with the given name
 func (c *APIGatewayProxyContext) GetCookie(name string) (http.Cookie, bool) {
-	rawCookies := c.GetHeader("cookie")
-	if rawCookies == "" {
-		return http.Cookie{}, false
-	}
-
-	rawRequest := fmt.Sprintf("GET / HTTP/1.0\r\nCookie: %s\r\n\r\n", rawCookies)
-	req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(rawRequest)))
-	if err != nil {
-		return http.Cookie{}, false
-	}
-
-	for _, cookie := range req.Cookies() {
-		if cookie.Name == name {
-			return *cookie, true
-		}
-	}
-
-	return http.Cookie{}, false
+	return lookupCookie([]string{c.GetHeader("cookie")}, name)
 }
 
 // GetHeader retrieves the header value by name. It canonicalizes headers to ensure that values can be accessed
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"testing"
@@ -13,6 +14,7 @@ import (
 	"github.com/PaesslerAG/jsonpath"
 	"github.com/aws/aws-lambda-go/events"
 	adapter "github.com/jfallis/lambda-proxy-http-adapter"
+	"github.com/rotisserie/eris"
 	"github.com/rs/zerolog"
 	"github.com/steinfletcher/apitest"
 	"github.com/stretchr/testify/assert"
@@ -55,10 +57,82 @@ func TestAPIGatewayProxyContext_Bind(t *testing.T) {
 				},
 			},
 			expectedBody: body{},
+			expectedErr: g8.Err{
+				Status:  400,
+				Code:    "INVALID_REQUEST_BODY",
+				Detail:  "Invalid request body",
+				Details: &g8.ErrDetails{Meta: map[string]interface{}{"offset": int64(1)}},
+			},
+		},
+		"invalid type": {
+			c: &g8.APIGatewayProxyContext{
+				Request: events.APIGatewayProxyRequest{
+					Body: `{"name":"one","status":2}`,
+				},
+			},
+			expectedBody: body{
+				Name: "one",
+			},
+			expectedErr: g8.Err{
+				Status: 400,
+				Code:   "INVALID_REQUEST_BODY",
+				Detail: "Invalid request body",
+				Details: &g8.ErrDetails{
+					Fields: []g8.FieldError{{
+						Field:   "status",
+						Code:    "INVALID_TYPE",
+						Message: "must be string but got number",
+					}},
+					Meta: map[string]interface{}{"offset": int64(24)},
+				},
+			},
+		},
+		"invalid root type": {
+			c: &g8.APIGatewayProxyContext{
+				Request: events.APIGatewayProxyRequest{
+					Body: `"one"`,
+				},
+			},
+			expectedBody: body{},
 			expectedErr: g8.Err{
 				Status: 400,
 				Code:   "INVALID_REQUEST_BODY",
 				Detail: "Invalid request body",
+				Details: &g8.ErrDetails{
+					Fields: []g8.FieldError{{
+						Field:   "$",
+						Code:    "INVALID_TYPE",
+						Message: "must be object but got string",
+					}},
+					Meta: map[string]interface{}{"offset": int64(5)},
+				},
+			},
+		},
+		"base64 encoded": {
+			c: &g8.APIGatewayProxyContext{
+				Request: events.APIGatewayProxyRequest{
+					Body:            `eyJuYW1lIjoib25lIiwic3RhdHVzIjoib2sifQ==`,
+					IsBase64Encoded: true,
+				},
+			},
+			expectedBody: body{
+				Name:   "one",
+				Status: "ok",
+			},
+			expectedErr: nil,
+		},
+		"invalid base64": {
+			c: &g8.APIGatewayProxyContext{
+				Request: events.APIGatewayProxyRequest{
+					Body:            `{"name":"one","status":"ok"}`,
+					IsBase64Encoded: true,
+				},
+			},
+			expectedBody: body{},
+			expectedErr: g8.Err{
+				Status: 400,
+				Code:   "INVALID_REQUEST_BODY",
+				Detail: "Invalid request body: invalid base64 encoding",
 			},
 		},
 		"validation error": {
@@ -190,6 +264,87 @@ func TestAPIGatewayProxyHandler_G8ErrorResponse(t *testing.T) {
 		End()
 }
 
+type joinedErrors []error
+
+func (e joinedErrors) Error() string   { return "joined errors" }
+func (e joinedErrors) Unwrap() []error { return e }
+
+func TestAPIGatewayProxyHandler_WrappedG8ErrorResponse(t *testing.T) {
+	notFound := g8.Err{
+		Status: http.StatusNotFound,
+		Code:   "NOT_FOUND",
+		Detail: "Not found",
+	}
+	badRequest := g8.Err{
+		Status: http.StatusBadRequest,
+		Code:   "BAD_REQUEST",
+		Detail: "Bad request",
+	}
+
+	testCases := map[string]struct {
+		err          error
+		expectedCode int
+		expectedBody string
+		expectedLog  bool
+	}{
+		"eris wrapped": {
+			err:          eris.Wrap(notFound, "failed to get user"),
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"code":"NOT_FOUND","detail":"Not found"}`,
+			expectedLog:  true,
+		},
+		"fmt wrapped": {
+			err:          fmt.Errorf("failed to get user: %w", notFound),
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"code":"NOT_FOUND","detail":"Not found"}`,
+			expectedLog:  true,
+		},
+		"pointer": {
+			err:          &badRequest,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"code":"BAD_REQUEST","detail":"Bad request"}`,
+		},
+		"wrapped pointer": {
+			err:          eris.Wrap(&badRequest, "invalid user"),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"code":"BAD_REQUEST","detail":"Bad request"}`,
+			expectedLog:  true,
+		},
+		"joined errors use the first err": {
+			err:          joinedErrors{errors.New("some error"), fmt.Errorf("context: %w", badRequest), notFound},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"code":"BAD_REQUEST","detail":"Bad request"}`,
+			expectedLog:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			logBuf := &bytes.Buffer{}
+			lh := g8.APIGatewayProxyHandler(func(c *g8.APIGatewayProxyContext) error {
+				return tc.err
+			}, g8.HandlerConfig{
+				Logger: zerolog.New(logBuf),
+			})
+
+			apitest.New().
+				Handler(adapter.GetHTTPHandlerWithContext(lh, "/", nil, nil)).
+				Get("/").
+				Expect(t).
+				Status(tc.expectedCode).
+				Body(tc.expectedBody).
+				End()
+
+			if !tc.expectedLog {
+				assert.Empty(t, logBuf.String())
+				return
+			}
+			assert.Equal(t, "Handled error", jsonPath("$.message", logBuf.Bytes()))
+			assert.Equal(t, "warn", jsonPath("$.level", logBuf.Bytes()))
+		})
+	}
+}
+
 func TestAPIGatewayProxyHandler_UnhandledErrorResponse(t *testing.T) {
 	h := func(c *g8.APIGatewayProxyContext) error {
 		return errors.New("some error")
@@ -273,3 +428,32 @@ func jsonPath(path string, jsonData []byte) interface{} {
 	}
 	return value
 }
+
+func TestAPIGatewayProxyHandler_PanicResponse(t *testing.T) {
+	h := func(c *g8.APIGatewayProxyContext) error {
+		panic("something went badly wrong")
+	}
+
+	logBuf := &bytes.Buffer{}
+	lh := g8.APIGatewayProxyHandler(h, g8.HandlerConfig{
+		Logger: zerolog.New(logBuf),
+	})
+
+	apitest.New().
+		Handler(adapter.GetHTTPHandlerWithContext(lh, "/", nil, nil)).
+		Get("/").
+		Header("Correlation-Id", "abcdef").
+		Expect(t).
+		Status(http.StatusInternalServerError).
+		Body(`{
+					"code": "INTERNAL_SERVER_ERROR",
+					"detail": "Internal server error"
+				}`).
+		Header("Correlation-Id", "abcdef").
+		End()
+
+	assert.Equal(t, "Unhandled error", jsonPath("$.message", logBuf.Bytes()))
+	assert.Equal(t, "abcdef", jsonPath("$.correlation_id", logBuf.Bytes()))
+	assert.Equal(t, "recovered panic: something went badly wrong", jsonPath("$.error.root.message", logBuf.Bytes()))
+	assert.NotEmpty(t, jsonPath("$.error.root.stack", logBuf.Bytes()))
+}
@@ -0,0 +1,227 @@
+package g8
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rotisserie/eris"
+)
+
+const (
+	contentTypeXML  = "application/xml"
+	contentTypeText = "text/plain"
+	contentTypeCSV  = "text/csv"
+	contentTypeHTML = "text/html"
+)
+
+var ErrNotAcceptable = Err{
+	Status: http.StatusNotAcceptable,
+	Code:   "NOT_ACCEPTABLE",
+	Detail: "Not acceptable",
+}
+
+// String writes a plain text response
+func (c *APIGatewayProxyContext) String(statusCode int, s string) error {
+	c.write(statusCode, contentTypeText+"; charset=utf-8", s)
+	return nil
+}
+
+// HTML writes an HTML response
+func (c *APIGatewayProxyContext) HTML(statusCode int, html string) error {
+	c.write(statusCode, contentTypeHTML+"; charset=utf-8", html)
+	return nil
+}
+
+// XML writes the body marshalled as XML
+func (c *APIGatewayProxyContext) XML(statusCode int, body interface{}) error {
+	s, err := marshalXML(body)
+	if err != nil {
+		return err
+	}
+	c.write(statusCode, contentTypeXML+"; charset=utf-8", s)
+	return nil
+}
+
+// CSV writes the records as a CSV response
+func (c *APIGatewayProxyContext) CSV(statusCode int, records [][]string) error {
+	buf := &bytes.Buffer{}
+	if err := csv.NewWriter(buf).WriteAll(records); err != nil {
+		return err
+	}
+	c.write(statusCode, contentTypeCSV+"; charset=utf-8", buf.String())
+	return nil
+}
+
+// Blob writes binary data, e.g. an image or PDF, with the given content type. The body is base64 encoded, which
+// API Gateway decodes when the request Accept header matches one of its binary media types.
+func (c *APIGatewayProxyContext) Blob(statusCode int, contentType string, b []byte) error {
+	c.write(statusCode, contentType, base64.StdEncoding.EncodeToString(b))
+	c.Response.IsBase64Encoded = true
+	return nil
+}
+
+// Attachment writes binary data as a file download with the given file name
+func (c *APIGatewayProxyContext) Attachment(statusCode int, contentType, filename string, b []byte) error {
+	c.setHeader("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	return c.Blob(statusCode, contentType, b)
+}
+
+// NoContent writes a response without a body, e.g. http.StatusNoContent, removing any Content-Type set before
+func (c *APIGatewayProxyContext) NoContent(statusCode int) error {
+	for k := range c.Response.Headers {
+		if strings.EqualFold(k, "Content-Type") {
+			delete(c.Response.Headers, k)
+		}
+	}
+	http.Header(c.Response.MultiValueHeaders).Del("Content-Type")
+	c.Response.StatusCode = statusCode
+	c.Response.Body = ""
+	c.Response.IsBase64Encoded = false
+	return nil
+}
+
+// Redirect writes a redirect to the location, the status code must be a 3xx redirect status
+func (c *APIGatewayProxyContext) Redirect(statusCode int, location string) error {
+	if statusCode < http.StatusMultipleChoices || statusCode > http.StatusPermanentRedirect {
+		return eris.Errorf("invalid redirect status code: %d", statusCode)
+	}
+	c.setHeader("Location", location)
+	c.Response.StatusCode = statusCode
+	c.Response.Body = ""
+	return nil
+}
+
+// Negotiate writes the body in the representation which best matches the request Accept header. JSON is offered
+// for every body, XML when the body can be marshalled as an XML element, e.g. not a map or nil pointer, CSV when the
+// body is a [][]string, and plain text when the body is a string or a fmt.Stringer. JSON is used when there is no
+// Accept header, and ErrNotAcceptable is returned when none of the offered representations are acceptable. A nil
+// body is written without a body or Content-Type, as by JSON. Vary: Accept is added to the response either way.
+func (c *APIGatewayProxyContext) Negotiate(statusCode int, body interface{}) error {
+	c.setHeader("Vary", addVary(responseHeader(c.Response, "Vary"), "Accept"))
+	if body == nil {
+		return c.JSON(statusCode, nil)
+	}
+
+	offers := []string{contentTypeJSON}
+	xmlBody, xmlErr := marshalXML(body)
+	if xmlErr == nil && xmlBody != xml.Header {
+		offers = append(offers, contentTypeXML, "text/xml")
+	}
+	records, isCSV := body.([][]string)
+	if isCSV {
+		offers = append(offers, contentTypeCSV)
+	}
+	switch body.(type) {
+	case string, fmt.Stringer:
+		offers = append(offers, contentTypeText)
+	}
+
+	switch negotiateContentType(c.GetHeader("Accept"), offers) {
+	case contentTypeJSON:
+		return c.JSON(statusCode, body)
+	case contentTypeXML, "text/xml":
+		c.write(statusCode, contentTypeXML+"; charset=utf-8", xmlBody)
+		return nil
+	case contentTypeCSV:
+		return c.CSV(statusCode, records)
+	case contentTypeText:
+		return c.String(statusCode, fmt.Sprint(body))
+	}
+	return ErrNotAcceptable
+}
+
+func marshalXML(body interface{}) (string, error) {
+	b, err := xml.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(b), nil
+}
+
+func (c *APIGatewayProxyContext) write(statusCode int, contentType, body string) {
+	c.setHeader("Content-Type", contentType)
+	c.Response.StatusCode = statusCode
+	c.Response.Body = body
+	c.Response.IsBase64Encoded = false
+}
+
+func (c *APIGatewayProxyContext) setHeader(key, value string) {
+	if c.Response.Headers == nil {
+		c.Response.Headers = make(map[string]string)
+	}
+	c.Response.Headers[key] = value
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// negotiateContentType returns the offer which best matches the Accept header, preferring offers in the order
+// they are given when they are equally acceptable. The first offer is returned when the header is empty, and
+// an empty string when no offer is acceptable.
+func negotiateContentType(accept string, offers []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	ranges := parseAcceptHeader(accept)
+	// the most specific ranges take precedence, e.g. text/plain over text/* over */*
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return strings.Count(ranges[i].mediaType, "*") < strings.Count(ranges[j].mediaType, "*")
+	})
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		for _, r := range ranges {
+			if !mediaTypeMatches(r.mediaType, offer) {
+				continue
+			}
+			if r.q > bestQ {
+				best, bestQ = offer, r.q
+			}
+			break
+		}
+	}
+	return best
+}
+
+// parseAcceptHeader parses the comma separated values of an Accept or Accept-Encoding header along with their
+// quality values, which default to 1
+func parseAcceptHeader(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(part, ";")
+		r := acceptRange{mediaType: strings.ToLower(strings.TrimSpace(value)), q: 1}
+		if r.mediaType == "" {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(k, "q") {
+				if q, err := strconv.ParseFloat(v, 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+func mediaTypeMatches(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	rangeType, rangeSubtype, _ := strings.Cut(mediaRange, "/")
+	offerType, _, _ := strings.Cut(mediaType, "/")
+	return rangeSubtype == "*" && rangeType == offerType
+}
@@ -0,0 +1,238 @@
+package g8_test
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JSainsburyPLC/g8"
+)
+
+type product struct {
+	XMLName xml.Name `json:"-" xml:"product"`
+	Name    string   `json:"name" xml:"name"`
+}
+
+func (p product) String() string {
+	return "product " + p.Name
+}
+
+func TestAPIGatewayProxyContext_ResponseWriters(t *testing.T) {
+	testCases := map[string]struct {
+		write               func(c *g8.APIGatewayProxyContext) error
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+		expectedBase64      bool
+		expectedHeaders     map[string]string
+	}{
+		"string": {
+			write:               func(c *g8.APIGatewayProxyContext) error { return c.String(http.StatusOK, "healthy") },
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody:        "healthy",
+		},
+		"html": {
+			write:               func(c *g8.APIGatewayProxyContext) error { return c.HTML(http.StatusOK, "<p>hello</p>") },
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/html; charset=utf-8",
+			expectedBody:        "<p>hello</p>",
+		},
+		"xml": {
+			write: func(c *g8.APIGatewayProxyContext) error {
+				return c.XML(http.StatusCreated, product{Name: "apple"})
+			},
+			expectedStatus:      http.StatusCreated,
+			expectedContentType: "application/xml; charset=utf-8",
+			expectedBody:        xml.Header + "<product><name>apple</name></product>",
+		},
+		"csv": {
+			write: func(c *g8.APIGatewayProxyContext) error {
+				return c.CSV(http.StatusOK, [][]string{{"name", "price"}, {"apple, red", "1.20"}})
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody:        "name,price\n\"apple, red\",1.20\n",
+		},
+		"blob": {
+			write: func(c *g8.APIGatewayProxyContext) error {
+				return c.Blob(http.StatusOK, "application/octet-stream", []byte("raw"))
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/octet-stream",
+			expectedBody:        "cmF3",
+			expectedBase64:      true,
+		},
+		"attachment": {
+			write: func(c *g8.APIGatewayProxyContext) error {
+				return c.Attachment(http.StatusOK, "application/pdf", "invoice 1.pdf", []byte("%PDF"))
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/pdf",
+			expectedBody:        "JVBERg==",
+			expectedBase64:      true,
+			expectedHeaders:     map[string]string{"Content-Disposition": `attachment; filename="invoice 1.pdf"`},
+		},
+		"no content": {
+			write:          func(c *g8.APIGatewayProxyContext) error { return c.NoContent(http.StatusNoContent) },
+			expectedStatus: http.StatusNoContent,
+		},
+		"no content after negotiate": {
+			write: func(c *g8.APIGatewayProxyContext) error {
+				if err := c.Negotiate(http.StatusOK, product{Name: "apple"}); err != nil {
+					return err
+				}
+				return c.NoContent(http.StatusNoContent)
+			},
+			expectedStatus:  http.StatusNoContent,
+			expectedHeaders: map[string]string{"Content-Type": "", "Vary": "Accept"},
+		},
+		"redirect": {
+			write: func(c *g8.APIGatewayProxyContext) error {
+				return c.Redirect(http.StatusFound, "https://example.com/login")
+			},
+			expectedStatus:  http.StatusFound,
+			expectedHeaders: map[string]string{"Location": "https://example.com/login"},
+		},
+		"invalid redirect": {
+			write: func(c *g8.APIGatewayProxyContext) error {
+				return c.Redirect(http.StatusOK, "https://example.com/login")
+			},
+			expectedStatus:      http.StatusInternalServerError,
+			expectedContentType: "application/json",
+			expectedBody:        `{"code":"INTERNAL_SERVER_ERROR","detail":"Internal server error"}`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			lh := g8.APIGatewayProxyHandler(tc.write, g8.HandlerConfig{BuildVersion: "1.0.0"})
+
+			res, err := lh(context.Background(), events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Correlation-Id": "abcdef"},
+			})
+
+			assert.Nil(t, err)
+			r := res.(events.APIGatewayProxyResponse)
+			assert.Equal(t, tc.expectedStatus, r.StatusCode)
+			assert.Equal(t, tc.expectedBody, r.Body)
+			assert.Equal(t, tc.expectedBase64, r.IsBase64Encoded)
+			if tc.expectedContentType != "" {
+				assert.Equal(t, tc.expectedContentType, r.Headers["Content-Type"])
+			}
+			for k, v := range tc.expectedHeaders {
+				assert.Equal(t, v, r.Headers[k])
+			}
+			assert.Equal(t, "abcdef", r.Headers["Correlation-Id"])
+			assert.Equal(t, "1.0.0", r.Headers["Build-Version"])
+		})
+	}
+}
+
+func TestAPIGatewayProxyContext_Negotiate(t *testing.T) {
+	testCases := map[string]struct {
+		accept              string
+		body                interface{}
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		"no accept header": {
+			body:                product{Name: "apple"},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+			expectedBody:        `{"name":"apple"}`,
+		},
+		"wildcard": {
+			accept:              "*/*",
+			body:                product{Name: "apple"},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+			expectedBody:        `{"name":"apple"}`,
+		},
+		"xml": {
+			accept:              "text/html, application/xml;q=0.9, */*;q=0.8",
+			body:                product{Name: "apple"},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/xml; charset=utf-8",
+			expectedBody:        xml.Header + "<product><name>apple</name></product>",
+		},
+		"quality": {
+			accept:              "application/json;q=0.5, text/plain",
+			body:                product{Name: "apple"},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody:        "product apple",
+		},
+		"specific range takes precedence": {
+			accept:              "text/*;q=0.9, text/csv;q=0.1, application/json;q=0.5",
+			body:                [][]string{{"name"}, {"apple"}},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/xml; charset=utf-8",
+			expectedBody:        xml.Header + "<string>name</string><string>apple</string>",
+		},
+		"csv": {
+			accept:              "text/csv",
+			body:                [][]string{{"name"}, {"apple"}},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody:        "name\napple\n",
+		},
+		"not acceptable": {
+			accept:              "text/csv",
+			body:                product{Name: "apple"},
+			expectedStatus:      http.StatusNotAcceptable,
+			expectedContentType: "application/json",
+			expectedBody:        `{"code":"NOT_ACCEPTABLE","detail":"Not acceptable"}`,
+		},
+		"xml not offered for map": {
+			accept:              "application/xml",
+			body:                map[string]string{"name": "apple"},
+			expectedStatus:      http.StatusNotAcceptable,
+			expectedContentType: "application/json",
+			expectedBody:        `{"code":"NOT_ACCEPTABLE","detail":"Not acceptable"}`,
+		},
+		"json for map": {
+			accept:              "application/xml, application/json;q=0.5",
+			body:                map[string]string{"name": "apple"},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+			expectedBody:        `{"name":"apple"}`,
+		},
+		"nil body": {
+			accept:         "application/xml",
+			body:           nil,
+			expectedStatus: http.StatusOK,
+		},
+		"xml not offered for nil pointer": {
+			accept:              "application/xml, application/json;q=0.5",
+			body:                (*product)(nil),
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+			expectedBody:        "null",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			lh := g8.APIGatewayProxyHandler(func(c *g8.APIGatewayProxyContext) error {
+				return c.Negotiate(http.StatusOK, tc.body)
+			}, g8.HandlerConfig{})
+
+			res, err := lh(context.Background(), events.APIGatewayProxyRequest{
+				MultiValueHeaders: map[string][]string{"Accept": {tc.accept}},
+			})
+
+			assert.Nil(t, err)
+			r := res.(events.APIGatewayProxyResponse)
+			assert.Equal(t, tc.expectedStatus, r.StatusCode)
+			assert.Equal(t, tc.expectedContentType, r.Headers["Content-Type"])
+			assert.Equal(t, tc.expectedBody, r.Body)
+			assert.Equal(t, "Accept", r.Headers["Vary"])
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package g8
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	newrelic "github.com/newrelic/go-agent"
+	"github.com/newrelic/go-agent/_integrations/nrlambda"
+	"github.com/rs/zerolog"
+)
+
+// APIGatewayV2HTTPContext is the context for API Gateway HTTP API requests using the 2.0 payload format
+type APIGatewayV2HTTPContext struct {
+	Context       context.Context
+	Request       events.APIGatewayV2HTTPRequest
+	Response      events.APIGatewayV2HTTPResponse
+	Logger        zerolog.Logger
+	NewRelicTx    newrelic.Transaction
+	CorrelationID string
+	validator     Validator
+	errorRenderer ErrorRenderer
+	maxBodySize   int64
+}
+
+type APIGatewayV2HTTPHandlerFunc func(c *APIGatewayV2HTTPContext) error
+
+func APIGatewayV2HTTPHandler(
+	h APIGatewayV2HTTPHandlerFunc,
+	conf HandlerConfig,
+) func(context.Context, events.APIGatewayV2HTTPRequest) (any, error) {
+	h = chainMiddleware(h, conf.APIGatewayV2HTTPMiddleware)
+	return func(ctx context.Context, r events.APIGatewayV2HTTPRequest) (any, error) {
+		correlationID := getCorrelationIDAPIGWV2(r.Headers)
+
+		logger := configureLogger(conf).
+			Str("route", r.RouteKey).
+			Str("correlation_id", correlationID).
+			Logger()
+
+		c := &APIGatewayV2HTTPContext{
+			Context:       ContextWithCorrelationID(ctx, correlationID),
+			Request:       r,
+			Logger:        logger,
+			NewRelicTx:    newrelic.FromContext(ctx),
+			CorrelationID: correlationID,
+			validator:     conf.Validator,
+			errorRenderer: conf.ErrorRenderer,
+			maxBodySize:   conf.MaxBodySize,
+		}
+
+		c.setHeader(headerCorrelationID, correlationID)
+		c.setHeader(headerBuildVersion, conf.BuildVersion)
+
+		c.AddNewRelicAttribute("functionName", conf.FunctionName)
+		c.AddNewRelicAttribute("route", r.RouteKey)
+		c.AddNewRelicAttribute("correlationID", correlationID)
+		c.AddNewRelicAttribute("buildVersion", conf.BuildVersion)
+
+		err := callHandler(c.NewRelicTx, func() error { return h(c) })
+		if err != nil {
+			c.handleError(err)
+		}
+
+		return c.Response, nil
+	}
+}
+
+func APIGatewayV2HTTPHandlerWithNewRelic(h APIGatewayV2HTTPHandlerFunc, conf HandlerConfig) lambda.Handler {
+	return nrlambda.Wrap(APIGatewayV2HTTPHandler(h, conf), conf.NewRelicApp)
+}
+
+func (c *APIGatewayV2HTTPContext) Bind(v interface{}) error {
+	b, err := c.Body()
+	if err != nil {
+		return err
+	}
+
+	return bindJSON(b, v, c.validator)
+}
+
+// Body returns the raw request body, decoding it when API Gateway has base64 encoded it and decompressing it
+// according to the Content-Encoding header. ErrRequestBodyTooLarge is returned when the body is larger than the
+// configured MaxBodySize.
+func (c *APIGatewayV2HTTPContext) Body() ([]byte, error) {
+	b, err := decodeBody(c.Request.Body, c.Request.IsBase64Encoded)
+	if err != nil {
+		return nil, err
+	}
+	return decompressBody(b, c.GetHeader("Content-Encoding"), c.maxBodySize)
+}
+
+func (c *APIGatewayV2HTTPContext) JSON(statusCode int, body interface{}) error {
+	var b []byte
+	var err error
+	if body != nil {
+		b, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		c.setHeader("Content-Type", contentTypeJSON)
+	}
+	c.Response.StatusCode = statusCode
+	c.Response.Body = string(b)
+	c.Response.IsBase64Encoded = false
+	return nil
+}
+
+// SetCookie adds a cookie to the response, which API Gateway sends as a Set-Cookie header
+func (c *APIGatewayV2HTTPContext) SetCookie(cookie http.Cookie) {
+	c.Response.Cookies = append(c.Response.Cookies, cookie.String())
+}
+
+func (c *APIGatewayV2HTTPContext) AddNewRelicAttribute(key string, val interface{}) {
+	if c.NewRelicTx == nil {
+		return
+	}
+	if err := c.NewRelicTx.AddAttribute(key, val); err != nil {
+		c.Logger.Error().Msgf("failed to add attr '%s' to new relic tx: %+v", key, err)
+	}
+}
+
+func (c *APIGatewayV2HTTPContext) handleError(err error) {
+	statusCode, contentType, b := renderHandlerError(c.Logger, c.errorRenderer, err, c.CorrelationID)
+	c.setHeader("Content-Type", contentType)
+	c.Response.StatusCode = statusCode
+	c.Response.Body = string(b)
+	c.Response.IsBase64Encoded = false
+}
+
+// GetCookie retrieves the cookie with the given name from the request Cookies
+func (c *APIGatewayV2HTTPContext) GetCookie(name string) (http.Cookie, bool) {
+	return lookupCookie(c.Request.Cookies, name)
+}
+
+// GetHeader retrieves the header value by name in a case insensitive manner. HTTP APIs combine the values of
+// headers sent more than once with commas.
+func (c *APIGatewayV2HTTPContext) GetHeader(name string) string {
+	return lookupHeader(c.Request.Headers, name)
+}
+
+func (c *APIGatewayV2HTTPContext) setHeader(key, value string) {
+	if c.Response.Headers == nil {
+		c.Response.Headers = make(map[string]string)
+	}
+	c.Response.Headers[key] = value
+}
+
+// lookupCookie retrieves the cookie with the given name from Cookie header values, e.g. "a=1" or "a=1; b=2"
+func lookupCookie(cookies []string, name string) (http.Cookie, bool) {
+	req := http.Request{Header: http.Header{"Cookie": cookies}}
+	cookie, err := req.Cookie(name)
+	if err != nil {
+		return http.Cookie{}, false
+	}
+	return *cookie, true
+}
+
+// lookupHeader retrieves a header value by name from a map of headers in a case insensitive manner
+func lookupHeader(headers map[string]string, name string) string {
+	if v, ok := headers[strings.ToLower(name)]; ok {
+		return v
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+func getCorrelationIDAPIGWV2(headers map[string]string) string {
+	correlationID := lookupHeader(headers, headerCorrelationID)
+	if correlationID != "" {
+		return correlationID
+	}
+	return uuid.New().String()
+}
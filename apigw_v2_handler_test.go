@@ -0,0 +1,225 @@
+package g8_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JSainsburyPLC/g8"
+)
+
+func TestAPIGatewayV2HTTPContext_Bind(t *testing.T) {
+	testCases := map[string]struct {
+		c            *g8.APIGatewayV2HTTPContext
+		expectedBody body
+		expectedErr  error
+	}{
+		"success": {
+			c: &g8.APIGatewayV2HTTPContext{
+				Request: events.APIGatewayV2HTTPRequest{
+					Body: `{"name":"one","status":"ok"}`,
+				},
+			},
+			expectedBody: body{Name: "one", Status: "ok"},
+		},
+		"base64 encoded": {
+			c: &g8.APIGatewayV2HTTPContext{
+				Request: events.APIGatewayV2HTTPRequest{
+					Body:            `eyJuYW1lIjoib25lIiwic3RhdHVzIjoib2sifQ==`,
+					IsBase64Encoded: true,
+				},
+			},
+			expectedBody: body{Name: "one", Status: "ok"},
+		},
+		"invalid json": {
+			c: &g8.APIGatewayV2HTTPContext{
+				Request: events.APIGatewayV2HTTPRequest{
+					Body: `NOTJSON`,
+				},
+			},
+			expectedErr: g8.Err{
+				Status:  400,
+				Code:    "INVALID_REQUEST_BODY",
+				Detail:  "Invalid request body",
+				Details: &g8.ErrDetails{Meta: map[string]interface{}{"offset": int64(1)}},
+			},
+		},
+		"validation error": {
+			c: &g8.APIGatewayV2HTTPContext{
+				Request: events.APIGatewayV2HTTPRequest{
+					Body: `{"name":"one"}`,
+				},
+			},
+			expectedBody: body{Name: "one"},
+			expectedErr:  errors.New("status empty"),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var b body
+			err := tc.c.Bind(&b)
+
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedBody, b)
+		})
+	}
+}
+
+func TestAPIGatewayV2HTTPHandler_SuccessResponse(t *testing.T) {
+	h := func(c *g8.APIGatewayV2HTTPContext) error {
+		assert.Equal(t, "application/json", c.GetHeader("Content-Type"))
+		c.SetCookie(http.Cookie{Name: "session", Value: "abc", HttpOnly: true})
+		return c.JSON(http.StatusOK, body{Name: c.Request.PathParameters["id"], Status: "ok"})
+	}
+
+	lh := g8.APIGatewayV2HTTPHandler(h, g8.HandlerConfig{BuildVersion: "1.0.0"})
+
+	res, err := lh(context.Background(), events.APIGatewayV2HTTPRequest{
+		RouteKey:       "GET /items/{id}",
+		RawPath:        "/items/1",
+		Headers:        map[string]string{"content-type": "application/json", "correlation-id": "abcdef"},
+		PathParameters: map[string]string{"id": "1"},
+	})
+
+	assert.Nil(t, err)
+	r := res.(events.APIGatewayV2HTTPResponse)
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+	assert.JSONEq(t, `{"name":"1","status":"ok"}`, r.Body)
+	assert.Equal(t, "application/json", r.Headers["Content-Type"])
+	assert.Equal(t, "abcdef", r.Headers["Correlation-Id"])
+	assert.Equal(t, "1.0.0", r.Headers["Build-Version"])
+	assert.Equal(t, []string{"session=abc; HttpOnly"}, r.Cookies)
+}
+
+func TestAPIGatewayV2HTTPHandler_ErrorResponse(t *testing.T) {
+	testCases := map[string]struct {
+		err            error
+		expectedStatus int
+		expectedBody   string
+		expectedLog    string
+	}{
+		"g8 error": {
+			err:            g8.ErrNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"code":"NOT_FOUND","detail":"Not found"}`,
+		},
+		"unhandled error": {
+			err:            errors.New("some error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"code":"INTERNAL_SERVER_ERROR","detail":"Internal server error"}`,
+			expectedLog:    "Unhandled error",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var logBuf bytes.Buffer
+			lh := g8.APIGatewayV2HTTPHandler(func(c *g8.APIGatewayV2HTTPContext) error {
+				return tc.err
+			}, g8.HandlerConfig{Logger: zerolog.New(&logBuf)})
+
+			res, err := lh(context.Background(), events.APIGatewayV2HTTPRequest{})
+
+			assert.Nil(t, err)
+			r := res.(events.APIGatewayV2HTTPResponse)
+			assert.Equal(t, tc.expectedStatus, r.StatusCode)
+			assert.JSONEq(t, tc.expectedBody, r.Body)
+			assert.Equal(t, "application/json", r.Headers["Content-Type"])
+			assert.NotEmpty(t, r.Headers["Correlation-Id"])
+			if tc.expectedLog != "" {
+				assert.True(t, containsLogMessage(logBuf.String(), tc.expectedLog))
+			}
+		})
+	}
+}
+
+func TestAPIGatewayV2HTTPContext_GetCookie(t *testing.T) {
+	c := &g8.APIGatewayV2HTTPContext{
+		Request: events.APIGatewayV2HTTPRequest{
+			Cookies: []string{"session=abc", "theme=dark"},
+		},
+	}
+
+	cookie, ok := c.GetCookie("theme")
+	assert.True(t, ok)
+	assert.Equal(t, "dark", cookie.Value)
+
+	_, ok = c.GetCookie("missing")
+	assert.False(t, ok)
+}
+
+func TestAPIGatewayV2HTTPContext_GetHeader(t *testing.T) {
+	c := &g8.APIGatewayV2HTTPContext{
+		Request: events.APIGatewayV2HTTPRequest{
+			Headers: map[string]string{"x-tenant-id": "tenant-1", "Accept": "text/html,application/json"},
+		},
+	}
+
+	assert.Equal(t, "tenant-1", c.GetHeader("X-Tenant-Id"))
+	assert.Equal(t, "text/html,application/json", c.GetHeader("accept"))
+	assert.Equal(t, "", c.GetHeader("missing"))
+}
+
+func TestLambdaAdapter_APIGatewayV2HTTP(t *testing.T) {
+	var request events.APIGatewayV2HTTPRequest
+	l := g8.LambdaHandler{
+		Handler: func(ctx *g8.APIGatewayV2HTTPContext) error {
+			request = ctx.Request
+			var b body
+			if err := ctx.Bind(&b); err != nil {
+				return err
+			}
+			ctx.SetCookie(http.Cookie{Name: "session", Value: "abc"})
+			return ctx.JSON(http.StatusCreated, b)
+		},
+		Method:      http.MethodPost,
+		PathPattern: "/stores/{storeId}/items",
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/stores/1/items?tag=a&tag=b", strings.NewReader(`{"name":"one","status":"ok"}`))
+	r.Header.Set("Cookie", "theme=dark; lang=en")
+	r.Header.Set("Correlation-Id", "abcdef")
+	g8.LambdaAdapter(l)(w, r)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"name":"one","status":"ok"}`, w.Body.String())
+	assert.Equal(t, "session=abc", w.Header().Get("Set-Cookie"))
+
+	assert.Equal(t, "2.0", request.Version)
+	assert.Equal(t, "POST /stores/{storeId}/items", request.RouteKey)
+	assert.Equal(t, "/stores/1/items", request.RawPath)
+	assert.Equal(t, "tag=a&tag=b", request.RawQueryString)
+	assert.Equal(t, map[string]string{"tag": "a,b"}, request.QueryStringParameters)
+	assert.Equal(t, map[string]string{"storeId": "1"}, request.PathParameters)
+	assert.Equal(t, []string{"theme=dark", "lang=en"}, request.Cookies)
+	assert.Equal(t, "abcdef", request.Headers["correlation-id"])
+	assert.Equal(t, http.MethodPost, request.RequestContext.HTTP.Method)
+}
+
+func TestLambdaAdapter_APIGatewayV2HTTPError(t *testing.T) {
+	l := g8.LambdaHandler{
+		Handler: func(ctx *g8.APIGatewayV2HTTPContext) error {
+			return g8.ErrNotFound
+		},
+		Method:      http.MethodGet,
+		PathPattern: "/items/{id}",
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	g8.LambdaAdapter(l)(w, r)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"code":"NOT_FOUND","detail":"Not found"}`, w.Body.String())
+}
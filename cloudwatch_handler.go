@@ -22,6 +22,7 @@ type CloudWatchContext struct {
 type CloudWatchHandlerFunc func(c *CloudWatchContext) (LambdaResult, error)
 
 func CloudWatchHandler(h CloudWatchHandlerFunc, conf HandlerConfig) func(context.Context, events.CloudWatchEvent) (LambdaResult, error) {
+	h = chainMiddleware(h, conf.CloudWatchMiddleware)
 	return func(ctx context.Context, event events.CloudWatchEvent) (LambdaResult, error) {
 		correlationID := uuid.New().String()
 
@@ -37,7 +38,7 @@ func CloudWatchHandler(h CloudWatchHandlerFunc, conf HandlerConfig) func(context
 			Logger()
 
 		c := &CloudWatchContext{
-			Context:       ctx,
+			Context:       ContextWithCorrelationID(ctx, correlationID),
 			Event:         event,
 			Logger:        logger,
 			NewRelicTx:    newrelic.FromContext(ctx),
@@ -49,7 +50,11 @@ func CloudWatchHandler(h CloudWatchHandlerFunc, conf HandlerConfig) func(context
 		c.AddNewRelicAttribute("correlationID", correlationID)
 		c.AddNewRelicAttribute("cloudWatchResource", cloudWatchResource)
 
-		result, err := h(c)
+		var result LambdaResult
+		err := callHandler(c.NewRelicTx, func() (err error) {
+			result, err = h(c)
+			return err
+		})
 		if err != nil {
 			logUnhandledError(c.Logger, err)
 		}
@@ -61,6 +66,28 @@ func CloudWatchHandlerWithNewRelic(h CloudWatchHandlerFunc, conf HandlerConfig)
 	return nrlambda.Wrap(CloudWatchHandler(h, conf), conf.NewRelicApp)
 }
 
+// CloudWatchHandlerFuncOf is passed the event's detail bound to Detail
+type CloudWatchHandlerFuncOf[Detail any] func(c *CloudWatchContext, detail Detail) (LambdaResult, error)
+
+// CloudWatchHandlerOf is a CloudWatchHandler which binds and validates the event's detail before calling the handler,
+// failing with ErrInvalidBody or a validation error when it can't be bound
+func CloudWatchHandlerOf[Detail any](
+	h CloudWatchHandlerFuncOf[Detail],
+	conf HandlerConfig,
+) func(context.Context, events.CloudWatchEvent) (LambdaResult, error) {
+	return CloudWatchHandler(func(c *CloudWatchContext) (LambdaResult, error) {
+		var detail Detail
+		if err := bindJSON(c.Event.Detail, &detail, conf.Validator); err != nil {
+			return nil, err
+		}
+		return h(c, detail)
+	}, conf)
+}
+
+func CloudWatchHandlerOfWithNewRelic[Detail any](h CloudWatchHandlerFuncOf[Detail], conf HandlerConfig) lambda.Handler {
+	return nrlambda.Wrap(CloudWatchHandlerOf(h, conf), conf.NewRelicApp)
+}
+
 func (c *CloudWatchContext) AddNewRelicAttribute(key string, val interface{}) {
 	if c.NewRelicTx == nil {
 		return
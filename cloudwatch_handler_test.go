@@ -48,3 +48,41 @@ func TestCloudWatchHandler_HandlerError(t *testing.T) {
 	assert.Equal(t, assert.AnError, err)
 	assert.Equal(t, 1, timesCalled)
 }
+
+func TestCloudWatchHandler_HandlerPanic(t *testing.T) {
+	handlerFunc := func(c *g8.CloudWatchContext) (g8.LambdaResult, error) {
+		var m map[string]string
+		m["key"] = "value"
+		return nil, nil
+	}
+
+	h := g8.CloudWatchHandler(handlerFunc, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+	})
+	result, err := h(context.Background(), events.CloudWatchEvent{})
+
+	assert.Nil(t, result)
+	assert.ErrorContains(t, err, "recovered panic: assignment to entry in nil map")
+}
+
+type scheduleDetail struct {
+	Job string `json:"job" validate:"required"`
+}
+
+func TestCloudWatchHandlerOf(t *testing.T) {
+	h := g8.CloudWatchHandlerOf(func(c *g8.CloudWatchContext, detail scheduleDetail) (g8.LambdaResult, error) {
+		return "ran " + detail.Job, nil
+	}, g8.HandlerConfig{
+		Logger:    zerolog.New(io.Discard),
+		Validator: g8.TagValidator{},
+	})
+
+	result, err := h(context.Background(), events.CloudWatchEvent{Detail: []byte(`{"job": "cleanup"}`)})
+	assert.Nil(t, err)
+	assert.Equal(t, "ran cleanup", result)
+
+	_, err = h(context.Background(), events.CloudWatchEvent{Detail: []byte(`{}`)})
+	var gErr g8.Err
+	assert.ErrorAs(t, err, &gErr)
+	assert.Equal(t, "VALIDATION_ERROR", gErr.Code)
+}
@@ -0,0 +1,194 @@
+package g8
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	encodingBrotli  = "br"
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+
+	defaultCompressionMinSize = 1024
+)
+
+// CompressionConfig configures compression of HTTP response bodies
+type CompressionConfig struct {
+	// Enabled turns on brotli and gzip compression for requests with a matching Accept-Encoding header
+	Enabled bool
+	// MinSize is the size in bytes below which bodies aren't compressed, defaulting to 1024
+	MinSize int
+}
+
+func (c CompressionConfig) minSize() int {
+	if c.MinSize <= 0 {
+		return defaultCompressionMinSize
+	}
+	return c.MinSize
+}
+
+// compressResponse compresses the response body with the encoding which best matches the Accept-Encoding header,
+// base64 encoding the compressed body. Responses which are empty, already encoded or smaller than the minimum size
+// are left as they are.
+func compressResponse(r *events.APIGatewayProxyResponse, acceptEncoding string, conf CompressionConfig) error {
+	if !conf.Enabled || r.Body == "" || responseHeader(*r, "Content-Encoding") != "" {
+		return nil
+	}
+
+	body := []byte(r.Body)
+	if r.IsBase64Encoded {
+		b, err := base64.StdEncoding.DecodeString(r.Body)
+		if err != nil {
+			return err
+		}
+		body = b
+	}
+	if len(body) < conf.minSize() {
+		return nil
+	}
+
+	if r.Headers == nil {
+		r.Headers = make(map[string]string)
+	}
+	r.Headers["Vary"] = addVary(responseHeader(*r, "Vary"), "Accept-Encoding")
+
+	encoding := negotiateContentEncoding(acceptEncoding, []string{encodingBrotli, encodingGzip})
+	if encoding == "" {
+		return nil
+	}
+
+	b, err := compress(encoding, body)
+	if err != nil {
+		return err
+	}
+
+	r.Headers["Content-Encoding"] = encoding
+	r.Body = base64.StdEncoding.EncodeToString(b)
+	r.IsBase64Encoded = true
+	return nil
+}
+
+func compress(encoding string, body []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	var w io.WriteCloser
+	switch encoding {
+	case encodingBrotli:
+		w = brotli.NewWriter(buf)
+	default:
+		w = gzip.NewWriter(buf)
+	}
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// negotiateContentEncoding returns the offer which best matches the Accept-Encoding header, preferring offers in
+// the order they are given when they are equally acceptable, or an empty string when none are acceptable
+func negotiateContentEncoding(acceptEncoding string, offers []string) string {
+	ranges := parseAcceptHeader(acceptEncoding)
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, matched := 0.0, false
+		for _, r := range ranges {
+			if r.mediaType == offer {
+				q, matched = r.q, true
+				break
+			}
+			if r.mediaType == "*" {
+				q, matched = r.q, true
+			}
+		}
+		if matched && q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// responseHeader retrieves a response header value by name in a case insensitive manner
+func responseHeader(r events.APIGatewayProxyResponse, name string) string {
+	for k, v := range r.Headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return http.Header(r.MultiValueHeaders).Get(name)
+}
+
+func addVary(vary, header string) string {
+	for _, v := range strings.Split(vary, ",") {
+		if strings.EqualFold(strings.TrimSpace(v), header) {
+			return vary
+		}
+	}
+	if vary == "" {
+		return header
+	}
+	return vary + ", " + header
+}
+
+// defaultMaxDecompressedBodySize limits decompressed request bodies when the max body size isn't configured
+const defaultMaxDecompressedBodySize = 10 << 20
+
+// decompressBody decompresses a gzip or deflate encoded request body, returning ErrRequestBodyTooLarge when the
+// decompressed body is larger than the max size. Compressed bodies are limited to defaultMaxDecompressedBodySize when
+// the max size is zero.
+func decompressBody(b []byte, contentEncoding string, maxSize int64) ([]byte, error) {
+	encoding := strings.ToLower(strings.TrimSpace(contentEncoding))
+
+	var r io.Reader
+	switch encoding {
+	case "", "identity":
+		r = bytes.NewReader(b)
+	case encodingGzip, "x-gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, errInvalidContentEncoding(encoding)
+		}
+		r = zr
+	case encodingDeflate:
+		zr, err := zlib.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, errInvalidContentEncoding(encoding)
+		}
+		r = zr
+	default:
+		return nil, ErrUnsupportedContentEncoding
+	}
+
+	if compressed := encoding != "" && encoding != "identity"; compressed && maxSize <= 0 {
+		maxSize = defaultMaxDecompressedBodySize
+	}
+	if maxSize > 0 {
+		r = io.LimitReader(r, maxSize+1)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errInvalidContentEncoding(encoding)
+	}
+	if maxSize > 0 && int64(len(body)) > maxSize {
+		return nil, ErrRequestBodyTooLarge.WithMeta(map[string]interface{}{"max_size": maxSize})
+	}
+	return body, nil
+}
+
+func errInvalidContentEncoding(encoding string) Err {
+	newErr := ErrInvalidBody
+	newErr.Detail = fmt.Sprintf("%s: invalid %s encoding", ErrInvalidBody.Detail, encoding)
+	return newErr
+}
@@ -0,0 +1,305 @@
+package g8_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/JSainsburyPLC/g8"
+)
+
+func TestAPIGatewayProxyHandler_Compression(t *testing.T) {
+	largeBody := strings.Repeat("product ", 200)
+
+	testCases := map[string]struct {
+		compression      g8.CompressionConfig
+		acceptEncoding   string
+		body             string
+		expectedEncoding string
+		expectedVary     string
+	}{
+		"gzip": {
+			compression:      g8.CompressionConfig{Enabled: true},
+			acceptEncoding:   "gzip, deflate",
+			body:             largeBody,
+			expectedEncoding: "gzip",
+			expectedVary:     "Accept-Encoding",
+		},
+		"brotli preferred": {
+			compression:      g8.CompressionConfig{Enabled: true},
+			acceptEncoding:   "gzip, deflate, br",
+			body:             largeBody,
+			expectedEncoding: "br",
+			expectedVary:     "Accept-Encoding",
+		},
+		"quality": {
+			compression:      g8.CompressionConfig{Enabled: true},
+			acceptEncoding:   "br;q=0.5, gzip",
+			body:             largeBody,
+			expectedEncoding: "gzip",
+			expectedVary:     "Accept-Encoding",
+		},
+		"wildcard": {
+			compression:      g8.CompressionConfig{Enabled: true},
+			acceptEncoding:   "*, br;q=0",
+			body:             largeBody,
+			expectedEncoding: "gzip",
+			expectedVary:     "Accept-Encoding",
+		},
+		"not accepted": {
+			compression:    g8.CompressionConfig{Enabled: true},
+			acceptEncoding: "identity",
+			body:           largeBody,
+			expectedVary:   "Accept-Encoding",
+		},
+		"below threshold": {
+			compression:    g8.CompressionConfig{Enabled: true},
+			acceptEncoding: "gzip",
+			body:           "small",
+		},
+		"custom threshold": {
+			compression:      g8.CompressionConfig{Enabled: true, MinSize: 5},
+			acceptEncoding:   "gzip",
+			body:             "small",
+			expectedEncoding: "gzip",
+			expectedVary:     "Accept-Encoding",
+		},
+		"disabled": {
+			acceptEncoding: "gzip",
+			body:           largeBody,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			lh := g8.APIGatewayProxyHandler(func(c *g8.APIGatewayProxyContext) error {
+				return c.String(http.StatusOK, tc.body)
+			}, g8.HandlerConfig{Compression: tc.compression})
+
+			res, err := lh(context.Background(), events.APIGatewayProxyRequest{
+				MultiValueHeaders: map[string][]string{"Accept-Encoding": {tc.acceptEncoding}},
+			})
+
+			assert.Nil(t, err)
+			r := res.(events.APIGatewayProxyResponse)
+			assert.Equal(t, http.StatusOK, r.StatusCode)
+			assert.Equal(t, tc.expectedEncoding, r.Headers["Content-Encoding"])
+			assert.Equal(t, tc.expectedVary, r.Headers["Vary"])
+			assert.Equal(t, "text/plain; charset=utf-8", r.Headers["Content-Type"])
+			if tc.expectedEncoding == "" {
+				assert.False(t, r.IsBase64Encoded)
+				assert.Equal(t, tc.body, r.Body)
+				return
+			}
+			assert.True(t, r.IsBase64Encoded)
+			assert.Equal(t, tc.body, decompress(t, tc.expectedEncoding, r.Body))
+		})
+	}
+}
+
+func TestAPIGatewayProxyHandler_CompressionBinaryBody(t *testing.T) {
+	body := bytes.Repeat([]byte{0x00, 0xff}, 1024)
+	lh := g8.APIGatewayProxyHandler(func(c *g8.APIGatewayProxyContext) error {
+		c.Response.Headers["Vary"] = "Origin"
+		return c.Blob(http.StatusOK, "application/octet-stream", body)
+	}, g8.HandlerConfig{Compression: g8.CompressionConfig{Enabled: true}})
+
+	res, err := lh(context.Background(), events.APIGatewayProxyRequest{
+		MultiValueHeaders: map[string][]string{"Accept-Encoding": {"gzip"}},
+	})
+
+	assert.Nil(t, err)
+	r := res.(events.APIGatewayProxyResponse)
+	assert.Equal(t, "gzip", r.Headers["Content-Encoding"])
+	assert.Equal(t, "Origin, Accept-Encoding", r.Headers["Vary"])
+	assert.Equal(t, string(body), decompress(t, "gzip", r.Body))
+}
+
+func TestLambdaAdapter_Compression(t *testing.T) {
+	body := strings.Repeat(`{"name":"apple"}`, 100)
+	l := g8.LambdaHandler{
+		Handler: func(ctx *g8.APIGatewayProxyContext) error {
+			return ctx.String(http.StatusOK, body)
+		},
+		Method:      http.MethodGet,
+		PathPattern: "/products",
+		Config: g8.HandlerConfig{
+			Compression: g8.CompressionConfig{Enabled: true},
+		},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/products", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	g8.LambdaAdapter(l)(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Equal(t, body, decompress(t, "gzip", base64.StdEncoding.EncodeToString(w.Body.Bytes())))
+}
+
+func decompress(t *testing.T, encoding, body string) string {
+	b, err := base64.StdEncoding.DecodeString(body)
+	require.NoError(t, err)
+
+	var r io.Reader
+	switch encoding {
+	case "br":
+		r = brotli.NewReader(bytes.NewReader(b))
+	default:
+		r, err = gzip.NewReader(bytes.NewReader(b))
+		require.NoError(t, err)
+	}
+	decoded, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(decoded)
+}
+
+func TestAPIGatewayProxyContext_BindCompressedBody(t *testing.T) {
+	type item struct {
+		Name string `json:"name"`
+	}
+	body := `{"name":"apple"}`
+
+	testCases := map[string]struct {
+		request      events.APIGatewayProxyRequest
+		maxBodySize  int64
+		expectedItem item
+		expectedErr  error
+	}{
+		"gzip": {
+			request:      compressedRequest(t, "gzip", body),
+			expectedItem: item{Name: "apple"},
+		},
+		"deflate": {
+			request:      compressedRequest(t, "deflate", body),
+			expectedItem: item{Name: "apple"},
+		},
+		"within max size": {
+			request:      compressedRequest(t, "gzip", body),
+			maxBodySize:  int64(len(body)),
+			expectedItem: item{Name: "apple"},
+		},
+		"decompressed body too large": {
+			request:     compressedRequest(t, "gzip", body),
+			maxBodySize: 10,
+			expectedErr: g8.Err{
+				Status:  http.StatusRequestEntityTooLarge,
+				Code:    "REQUEST_BODY_TOO_LARGE",
+				Detail:  "Request body too large",
+				Details: &g8.ErrDetails{Meta: map[string]interface{}{"max_size": int64(10)}},
+			},
+		},
+		"decompressed body over default limit": {
+			request: compressedRequest(t, "gzip", strings.Repeat(" ", 11<<20)),
+			expectedErr: g8.Err{
+				Status:  http.StatusRequestEntityTooLarge,
+				Code:    "REQUEST_BODY_TOO_LARGE",
+				Detail:  "Request body too large",
+				Details: &g8.ErrDetails{Meta: map[string]interface{}{"max_size": int64(10 << 20)}},
+			},
+		},
+		"uncompressed body too large": {
+			request:     events.APIGatewayProxyRequest{Body: body},
+			maxBodySize: 10,
+			expectedErr: g8.Err{
+				Status:  http.StatusRequestEntityTooLarge,
+				Code:    "REQUEST_BODY_TOO_LARGE",
+				Detail:  "Request body too large",
+				Details: &g8.ErrDetails{Meta: map[string]interface{}{"max_size": int64(10)}},
+			},
+		},
+		"invalid gzip": {
+			request: events.APIGatewayProxyRequest{
+				Body:              body,
+				MultiValueHeaders: map[string][]string{"Content-Encoding": {"gzip"}},
+			},
+			expectedErr: g8.Err{
+				Status: http.StatusBadRequest,
+				Code:   "INVALID_REQUEST_BODY",
+				Detail: "Invalid request body: invalid gzip encoding",
+			},
+		},
+		"unsupported encoding": {
+			request: events.APIGatewayProxyRequest{
+				Body:              body,
+				MultiValueHeaders: map[string][]string{"Content-Encoding": {"compress"}},
+			},
+			expectedErr: g8.ErrUnsupportedContentEncoding,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var i item
+			var err error
+			lh := g8.APIGatewayProxyHandler(func(c *g8.APIGatewayProxyContext) error {
+				err = c.Bind(&i)
+				return nil
+			}, g8.HandlerConfig{MaxBodySize: tc.maxBodySize})
+
+			_, _ = lh(context.Background(), tc.request)
+
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedItem, i)
+		})
+	}
+}
+
+func TestLambdaAdapter_MaxBodySize(t *testing.T) {
+	l := g8.LambdaHandler{
+		Handler: func(ctx *g8.APIGatewayProxyContext) error {
+			var v map[string]interface{}
+			return ctx.Bind(&v)
+		},
+		Method:      http.MethodPost,
+		PathPattern: "/products",
+		Config: g8.HandlerConfig{
+			MaxBodySize: 10,
+		},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(`{"name":"apple"}`))
+	g8.LambdaAdapter(l)(w, r)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.JSONEq(t, `{
+		"code": "REQUEST_BODY_TOO_LARGE",
+		"detail": "Request body too large",
+		"meta": {"max_size": 10}
+	}`, w.Body.String())
+}
+
+func compressedRequest(t *testing.T, encoding, body string) events.APIGatewayProxyRequest {
+	buf := &bytes.Buffer{}
+	var w io.WriteCloser
+	switch encoding {
+	case "deflate":
+		w = zlib.NewWriter(buf)
+	default:
+		w = gzip.NewWriter(buf)
+	}
+	_, err := w.Write([]byte(body))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return events.APIGatewayProxyRequest{
+		Body:              base64.StdEncoding.EncodeToString(buf.Bytes()),
+		IsBase64Encoded:   true,
+		MultiValueHeaders: map[string][]string{"Content-Encoding": {encoding}},
+	}
+}
@@ -0,0 +1,150 @@
+package g8
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var defaultCORSMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// CORSConfig configures cross-origin resource sharing for HTTP handlers
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to make cross-origin requests. An origin may be "*" to allow any
+	// origin, or contain a wildcard subdomain, e.g. "https://*.example.com". CORS is disabled when it is empty.
+	AllowedOrigins []string
+	// AllowedMethods defaults to GET, HEAD, POST, PUT, PATCH and DELETE
+	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed in cross-origin requests, defaulting to the headers asked
+	// for by the preflight request
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers which browsers make available to scripts
+	ExposedHeaders []string
+	// AllowCredentials allows cookies and authorization headers to be sent in cross-origin requests
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache preflight responses
+	MaxAge time.Duration
+}
+
+func (c CORSConfig) enabled() bool {
+	return len(c.AllowedOrigins) > 0
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for the request origin, and false when the origin
+// isn't allowed
+func (c CORSConfig) allowOrigin(origin string) (string, bool) {
+	if origin == "" {
+		return "", false
+	}
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			if c.AllowCredentials {
+				return origin, true
+			}
+			return "*", true
+		}
+		if originMatches(o, origin) {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// responseHeaders returns the CORS headers for an actual, i.e. not preflight, request from the origin
+func (c CORSConfig) responseHeaders(origin string) map[string]string {
+	headers := c.originHeaders(origin)
+	if headers == nil {
+		return nil
+	}
+	if len(c.ExposedHeaders) > 0 {
+		headers["Access-Control-Expose-Headers"] = strings.Join(c.ExposedHeaders, ", ")
+	}
+	return headers
+}
+
+// preflightHeaders returns the CORS headers for a preflight request from the origin
+func (c CORSConfig) preflightHeaders(origin, requestHeaders string) map[string]string {
+	headers := c.originHeaders(origin)
+	if headers == nil {
+		return nil
+	}
+
+	methods := c.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	headers["Access-Control-Allow-Methods"] = strings.Join(methods, ", ")
+
+	allowedHeaders := strings.Join(c.AllowedHeaders, ", ")
+	if len(c.AllowedHeaders) == 0 {
+		allowedHeaders = requestHeaders
+	}
+	if allowedHeaders != "" {
+		headers["Access-Control-Allow-Headers"] = allowedHeaders
+	}
+
+	if c.MaxAge > 0 {
+		headers["Access-Control-Max-Age"] = strconv.Itoa(int(c.MaxAge.Seconds()))
+	}
+	return headers
+}
+
+func (c CORSConfig) originHeaders(origin string) map[string]string {
+	allowOrigin, ok := c.allowOrigin(origin)
+	if !ok {
+		return nil
+	}
+
+	headers := map[string]string{"Access-Control-Allow-Origin": allowOrigin}
+	if c.AllowCredentials {
+		headers["Access-Control-Allow-Credentials"] = "true"
+	}
+	return headers
+}
+
+// originMatches reports whether the origin matches the allowed origin, which may contain a single wildcard
+func originMatches(allowed, origin string) bool {
+	prefix, suffix, wildcard := strings.Cut(strings.ToLower(allowed), "*")
+	origin = strings.ToLower(origin)
+	if !wildcard {
+		return origin == prefix
+	}
+	return len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
+
+func isPreflightRequest(method, origin, requestMethod string) bool {
+	return method == http.MethodOptions && origin != "" && requestMethod != ""
+}
+
+// applyCORSHeaders adds the CORS headers to the response. Vary: Origin is added for every response when CORS is
+// enabled, as the headers depend on the request origin.
+func applyCORSHeaders(r *events.APIGatewayProxyResponse, conf CORSConfig, headers map[string]string) {
+	if !conf.enabled() {
+		return
+	}
+	if r.Headers == nil {
+		r.Headers = make(map[string]string)
+	}
+	for k, v := range headers {
+		r.Headers[k] = v
+	}
+	r.Headers["Vary"] = addVary(responseHeader(*r, "Vary"), "Origin")
+}
+
+// corsPreflight returns the response to a preflight request, which is an empty 204 response with the CORS headers
+// added when the origin is allowed
+func corsPreflight(conf CORSConfig, origin, requestHeaders string) events.APIGatewayProxyResponse {
+	r := events.APIGatewayProxyResponse{StatusCode: http.StatusNoContent}
+	applyCORSHeaders(&r, conf, conf.preflightHeaders(origin, requestHeaders))
+	return r
+}
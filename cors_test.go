@@ -0,0 +1,241 @@
+package g8_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JSainsburyPLC/g8"
+)
+
+func TestAPIGatewayProxyHandler_CORS(t *testing.T) {
+	testCases := map[string]struct {
+		cors            g8.CORSConfig
+		method          string
+		headers         map[string][]string
+		handlerErr      error
+		expectedStatus  int
+		expectedHeaders map[string]string
+		expectedCalled  bool
+	}{
+		"allowed origin": {
+			cors: g8.CORSConfig{
+				AllowedOrigins: []string{"https://shop.example.com"},
+				ExposedHeaders: []string{"Correlation-Id", "Build-Version"},
+			},
+			method:         http.MethodGet,
+			headers:        map[string][]string{"Origin": {"https://shop.example.com"}},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":   "https://shop.example.com",
+				"Access-Control-Expose-Headers": "Correlation-Id, Build-Version",
+				"Vary":                          "Origin",
+			},
+			expectedCalled: true,
+		},
+		"wildcard subdomain": {
+			cors:           g8.CORSConfig{AllowedOrigins: []string{"https://*.example.com"}},
+			method:         http.MethodGet,
+			headers:        map[string][]string{"Origin": {"https://admin.example.com"}},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "https://admin.example.com",
+				"Vary":                        "Origin",
+			},
+			expectedCalled: true,
+		},
+		"wildcard subdomain does not match domain": {
+			cors:           g8.CORSConfig{AllowedOrigins: []string{"https://*.example.com"}},
+			method:         http.MethodGet,
+			headers:        map[string][]string{"Origin": {"https://example.com"}},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+				"Vary":                        "Origin",
+			},
+			expectedCalled: true,
+		},
+		"origin not allowed": {
+			cors:           g8.CORSConfig{AllowedOrigins: []string{"https://shop.example.com"}},
+			method:         http.MethodGet,
+			headers:        map[string][]string{"Origin": {"https://evil.example.org"}},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+				"Vary":                        "Origin",
+			},
+			expectedCalled: true,
+		},
+		"any origin": {
+			cors:           g8.CORSConfig{AllowedOrigins: []string{"*"}},
+			method:         http.MethodGet,
+			headers:        map[string][]string{"Origin": {"https://shop.example.com"}},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "*",
+				"Access-Control-Allow-Credentials": "",
+			},
+			expectedCalled: true,
+		},
+		"any origin with credentials": {
+			cors:           g8.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			method:         http.MethodGet,
+			headers:        map[string][]string{"Origin": {"https://shop.example.com"}},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://shop.example.com",
+				"Access-Control-Allow-Credentials": "true",
+			},
+			expectedCalled: true,
+		},
+		"error response": {
+			cors:           g8.CORSConfig{AllowedOrigins: []string{"https://shop.example.com"}},
+			method:         http.MethodGet,
+			headers:        map[string][]string{"Origin": {"https://shop.example.com"}},
+			handlerErr:     errors.New("some error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "https://shop.example.com",
+			},
+			expectedCalled: true,
+		},
+		"preflight": {
+			cors: g8.CORSConfig{
+				AllowedOrigins: []string{"https://shop.example.com"},
+				MaxAge:         10 * time.Minute,
+			},
+			method: http.MethodOptions,
+			headers: map[string][]string{
+				"Origin":                         {"https://shop.example.com"},
+				"Access-Control-Request-Method":  {"POST"},
+				"Access-Control-Request-Headers": {"Content-Type, X-Tenant-Id"},
+			},
+			expectedStatus: http.StatusNoContent,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "https://shop.example.com",
+				"Access-Control-Allow-Methods": "GET, HEAD, POST, PUT, PATCH, DELETE",
+				"Access-Control-Allow-Headers": "Content-Type, X-Tenant-Id",
+				"Access-Control-Max-Age":       "600",
+				"Vary":                         "Origin",
+			},
+		},
+		"preflight with configured methods and headers": {
+			cors: g8.CORSConfig{
+				AllowedOrigins:   []string{"https://shop.example.com"},
+				AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+				AllowedHeaders:   []string{"Content-Type"},
+				AllowCredentials: true,
+			},
+			method: http.MethodOptions,
+			headers: map[string][]string{
+				"Origin":                         {"https://shop.example.com"},
+				"Access-Control-Request-Method":  {"POST"},
+				"Access-Control-Request-Headers": {"Content-Type, X-Tenant-Id"},
+			},
+			expectedStatus: http.StatusNoContent,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://shop.example.com",
+				"Access-Control-Allow-Methods":     "GET, POST",
+				"Access-Control-Allow-Headers":     "Content-Type",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Max-Age":           "",
+			},
+		},
+		"preflight origin not allowed": {
+			cors:   g8.CORSConfig{AllowedOrigins: []string{"https://shop.example.com"}},
+			method: http.MethodOptions,
+			headers: map[string][]string{
+				"Origin":                        {"https://evil.example.org"},
+				"Access-Control-Request-Method": {"POST"},
+			},
+			expectedStatus: http.StatusNoContent,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+		"options without cors": {
+			method: http.MethodOptions,
+			headers: map[string][]string{
+				"Origin":                        {"https://shop.example.com"},
+				"Access-Control-Request-Method": {"POST"},
+			},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+				"Vary":                        "",
+			},
+			expectedCalled: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			called := false
+			lh := g8.APIGatewayProxyHandler(func(c *g8.APIGatewayProxyContext) error {
+				called = true
+				if tc.handlerErr != nil {
+					return tc.handlerErr
+				}
+				return c.NoContent(http.StatusOK)
+			}, g8.HandlerConfig{CORS: tc.cors})
+
+			res, err := lh(context.Background(), events.APIGatewayProxyRequest{
+				HTTPMethod:        tc.method,
+				MultiValueHeaders: tc.headers,
+			})
+
+			assert.Nil(t, err)
+			r := res.(events.APIGatewayProxyResponse)
+			assert.Equal(t, tc.expectedStatus, r.StatusCode)
+			assert.Equal(t, tc.expectedCalled, called)
+			for k, v := range tc.expectedHeaders {
+				assert.Equal(t, v, r.Headers[k], k)
+			}
+		})
+	}
+}
+
+func TestLambdaAdapter_CORS(t *testing.T) {
+	l := g8.LambdaHandler{
+		Handler: func(ctx *g8.APIGatewayProxyContext) error {
+			return ctx.JSON(http.StatusOK, map[string]string{"name": "apple"})
+		},
+		Method:      http.MethodGet,
+		PathPattern: "/products",
+		Config: g8.HandlerConfig{
+			CORS: g8.CORSConfig{AllowedOrigins: []string{"http://localhost:*"}},
+		},
+	}
+
+	t.Run("preflight", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodOptions, "/products", nil)
+		r.Header.Set("Origin", "http://localhost:3000")
+		r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		g8.LambdaAdapter(l)(w, r)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "http://localhost:3000", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "GET, HEAD, POST, PUT, PATCH, DELETE", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("request", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/products", nil)
+		r.Header.Set("Origin", "http://localhost:3000")
+		g8.LambdaAdapter(l)(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "http://localhost:3000", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "Origin", w.Header().Get("Vary"))
+		assert.JSONEq(t, `{"name":"apple"}`, w.Body.String())
+	})
+}
@@ -22,6 +22,7 @@ type DynamoDbContext struct {
 type DynamoHandlerFunc func(c *DynamoDbContext) error
 
 func DynamoDbHandler(h DynamoHandlerFunc, conf HandlerConfig) func(context.Context, events.DynamoDBEvent) error {
+	h = chainMiddleware(h, conf.DynamoDbMiddleware)
 	return func(ctx context.Context, e events.DynamoDBEvent) error {
 		for _, record := range e.Records {
 			correlationID := uuid.New().String()
@@ -32,7 +33,7 @@ func DynamoDbHandler(h DynamoHandlerFunc, conf HandlerConfig) func(context.Conte
 				Logger()
 
 			c := &DynamoDbContext{
-				Context:       ctx,
+				Context:       ContextWithCorrelationID(ctx, correlationID),
 				EventRecord:   record,
 				Logger:        logger,
 				NewRelicTx:    newrelic.FromContext(ctx),
@@ -44,7 +45,7 @@ func DynamoDbHandler(h DynamoHandlerFunc, conf HandlerConfig) func(context.Conte
 			c.AddNewRelicAttribute("correlationID", correlationID)
 			c.AddNewRelicAttribute("dynamoDBEventSource", record.EventSource)
 
-			if err := h(c); err != nil {
+			if err := callHandler(c.NewRelicTx, func() error { return h(c) }); err != nil {
 				logUnhandledError(c.Logger, err)
 				return err
 			}
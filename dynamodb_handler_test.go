@@ -89,3 +89,18 @@ func TestDynamoDbHandler_HandlerError(t *testing.T) {
 	assert.Equal(t, assert.AnError, err)
 	assert.Equal(t, 1, timesCalled)
 }
+
+func TestDynamoDbHandler_HandlerPanic(t *testing.T) {
+	handlerFunc := func(c *g8.DynamoDbContext) error {
+		panic("something went badly wrong")
+	}
+
+	h := g8.DynamoDbHandler(handlerFunc, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+	})
+	err := h(context.Background(), events.DynamoDBEvent{
+		Records: []events.DynamoDBEventRecord{{}},
+	})
+
+	assert.EqualError(t, err, "recovered panic: something went badly wrong")
+}
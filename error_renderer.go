@@ -0,0 +1,103 @@
+package g8
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	contentTypeJSON        = "application/json"
+	contentTypeProblemJSON = "application/problem+json"
+)
+
+// ErrorRenderer renders an Err as the body of an HTTP response. It is set on the HandlerConfig and used by every
+// handler which responds to HTTP requests, as well as the local LambdaAdapter. The response status is always the
+// Err status.
+type ErrorRenderer interface {
+	RenderError(err Err, correlationID string) (contentType string, body []byte, renderErr error)
+}
+
+// JSONErrorRenderer is the default ErrorRenderer, writing the Err as JSON, e.g. {"code":"...","detail":"..."}
+type JSONErrorRenderer struct{}
+
+func (JSONErrorRenderer) RenderError(err Err, _ string) (string, []byte, error) {
+	b, mErr := json.Marshal(err)
+	return contentTypeJSON, b, mErr
+}
+
+// ProblemDetailsRenderer writes errors as RFC 7807 application/problem+json. The Err code is mapped to the problem
+// type and title, e.g. VALIDATION_ERROR is given the type TypeBaseURI + "validation-error" and the title
+// "Validation error", and the correlation ID is used as the problem instance. The code, fields and meta of the
+// Err are written as extension members.
+type ProblemDetailsRenderer struct {
+	// TypeBaseURI is prefixed to the problem type, e.g. "https://errors.example.com/"
+	TypeBaseURI string
+}
+
+func (r ProblemDetailsRenderer) RenderError(err Err, correlationID string) (string, []byte, error) {
+	problem := make(map[string]interface{}, len(err.meta())+7)
+	for k, v := range err.meta() {
+		problem[k] = v
+	}
+	problem["type"] = r.TypeBaseURI + strings.ReplaceAll(strings.ToLower(err.Code), "_", "-")
+	problem["title"] = problemTitle(err.Code)
+	problem["status"] = err.Status
+	problem["code"] = err.Code
+	if err.Detail != "" {
+		problem["detail"] = err.Detail
+	}
+	if correlationID != "" {
+		problem["instance"] = correlationID
+	}
+	if fields := err.fields(); len(fields) > 0 {
+		problem["fields"] = fields
+	}
+
+	b, mErr := json.Marshal(problem)
+	return contentTypeProblemJSON, b, mErr
+}
+
+// problemTitle converts an error code into a human readable title, e.g. NOT_FOUND becomes "Not found"
+func problemTitle(code string) string {
+	title := strings.ToLower(strings.ReplaceAll(code, "_", " "))
+	if title == "" {
+		return title
+	}
+	return strings.ToUpper(title[:1]) + title[1:]
+}
+
+// renderError renders the Err with the renderer, falling back to the default JSON rendering of an internal server
+// error if it cannot be rendered
+func renderError(renderer ErrorRenderer, err Err, correlationID string) (int, string, []byte) {
+	if renderer == nil {
+		renderer = JSONErrorRenderer{}
+	}
+
+	contentType, b, rErr := renderer.RenderError(err, correlationID)
+	if rErr != nil {
+		b, _ = json.Marshal(ErrInternalServer)
+		return ErrInternalServer.Status, contentTypeJSON, b
+	}
+	return err.Status, contentType, b
+}
+
+// renderHandlerError logs the error returned by a handler and renders it. Errors which don't wrap an Err are logged
+// as unhandled and rendered as ErrInternalServer, while those which wrap one are logged with the context they add.
+func renderHandlerError(
+	logger zerolog.Logger,
+	renderer ErrorRenderer,
+	err error,
+	correlationID string,
+) (int, string, []byte) {
+	newErr, ok := asErr(err)
+	switch {
+	case !ok:
+		newErr = ErrInternalServer
+		logUnhandledError(logger, err)
+	case !isErr(err):
+		logWrappedError(logger, err)
+	}
+	return renderError(renderer, newErr, correlationID)
+}
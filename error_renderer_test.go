@@ -0,0 +1,128 @@
+package g8_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	adapter "github.com/jfallis/lambda-proxy-http-adapter"
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JSainsburyPLC/g8"
+)
+
+func TestProblemDetailsRenderer_RenderError(t *testing.T) {
+	testCases := map[string]struct {
+		renderer      g8.ProblemDetailsRenderer
+		err           g8.Err
+		correlationID string
+		expectedBody  string
+	}{
+		"minimal": {
+			err:          g8.ErrNotFound,
+			expectedBody: `{"type":"not-found","title":"Not found","status":404,"code":"NOT_FOUND","detail":"Not found"}`,
+		},
+		"type base uri and instance": {
+			renderer:      g8.ProblemDetailsRenderer{TypeBaseURI: "https://errors.example.com/"},
+			err:           g8.ErrInternalServer,
+			correlationID: "abcdef",
+			expectedBody: `{
+				"type": "https://errors.example.com/internal-server-error",
+				"title": "Internal server error",
+				"status": 500,
+				"code": "INTERNAL_SERVER_ERROR",
+				"detail": "Internal server error",
+				"instance": "abcdef"
+			}`,
+		},
+		"fields and meta extensions": {
+			err: g8.Err{
+				Status: http.StatusBadRequest,
+				Code:   "VALIDATION_ERROR",
+				Detail: "name: is required",
+				Details: &g8.ErrDetails{
+					Fields: []g8.FieldError{{Field: "name", Code: "REQUIRED", Message: "is required"}},
+					Meta:   map[string]interface{}{"retryable": false, "status": "ignored"},
+				},
+			},
+			expectedBody: `{
+				"type": "validation-error",
+				"title": "Validation error",
+				"status": 400,
+				"code": "VALIDATION_ERROR",
+				"detail": "name: is required",
+				"fields": [{"field": "name", "code": "REQUIRED", "message": "is required"}],
+				"retryable": false
+			}`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			contentType, b, err := tc.renderer.RenderError(tc.err, tc.correlationID)
+
+			assert.Nil(t, err)
+			assert.Equal(t, "application/problem+json", contentType)
+			assert.JSONEq(t, tc.expectedBody, string(b))
+		})
+	}
+}
+
+func TestAPIGatewayProxyHandler_ProblemDetailsErrorRenderer(t *testing.T) {
+	h := func(c *g8.APIGatewayProxyContext) error {
+		return g8.ErrValidation("invalid name")
+	}
+
+	lh := g8.APIGatewayProxyHandler(h, g8.HandlerConfig{
+		ErrorRenderer: g8.ProblemDetailsRenderer{TypeBaseURI: "https://errors.example.com/"},
+	})
+
+	apitest.New().
+		Handler(adapter.GetHTTPHandlerWithContext(lh, "/", nil, nil)).
+		Get("/").
+		Header("Correlation-Id", "abcdef").
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Header("Content-Type", "application/problem+json").
+		Header("Correlation-Id", "abcdef").
+		Body(`{
+			"type": "https://errors.example.com/validation-error",
+			"title": "Validation error",
+			"status": 400,
+			"code": "VALIDATION_ERROR",
+			"detail": "invalid name",
+			"instance": "abcdef"
+		}`).
+		End()
+}
+
+func TestLambdaAdapter_ProblemDetailsErrorRenderer(t *testing.T) {
+	l := g8.LambdaHandler{
+		Handler: func(ctx *g8.APIGatewayProxyContext) error {
+			return errors.New("some error")
+		},
+		Method:      http.MethodGet,
+		PathPattern: "/test/url/path",
+		Config: g8.HandlerConfig{
+			ErrorRenderer: g8.ProblemDetailsRenderer{},
+		},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/test/url/path", nil)
+	r.Header.Set("Correlation-Id", "abcdef")
+	g8.LambdaAdapter(l)(w, r)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"type": "internal-server-error",
+		"title": "Internal server error",
+		"status": 500,
+		"code": "INTERNAL_SERVER_ERROR",
+		"detail": "Internal server error",
+		"instance": "abcdef"
+	}`, w.Body.String())
+}
@@ -0,0 +1,324 @@
+package g8
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	newrelic "github.com/newrelic/go-agent"
+	"github.com/newrelic/go-agent/_integrations/nrlambda"
+	"github.com/rs/zerolog"
+)
+
+// FunctionURLContext is the context for requests to a Lambda Function URL using the BUFFERED invoke mode
+type FunctionURLContext struct {
+	Context       context.Context
+	Request       events.LambdaFunctionURLRequest
+	Response      events.LambdaFunctionURLResponse
+	Logger        zerolog.Logger
+	NewRelicTx    newrelic.Transaction
+	CorrelationID string
+	validator     Validator
+	errorRenderer ErrorRenderer
+	maxBodySize   int64
+}
+
+type FunctionURLHandlerFunc func(c *FunctionURLContext) error
+
+func FunctionURLHandler(
+	h FunctionURLHandlerFunc,
+	conf HandlerConfig,
+) func(context.Context, events.LambdaFunctionURLRequest) (any, error) {
+	h = chainMiddleware(h, conf.FunctionURLMiddleware)
+	return func(ctx context.Context, r events.LambdaFunctionURLRequest) (any, error) {
+		correlationID := getCorrelationIDFunctionURL(r.Headers)
+
+		c := &FunctionURLContext{
+			Context:       ContextWithCorrelationID(ctx, correlationID),
+			Request:       r,
+			Logger:        functionURLLogger(conf, r, correlationID),
+			NewRelicTx:    newrelic.FromContext(ctx),
+			CorrelationID: correlationID,
+			validator:     conf.Validator,
+			errorRenderer: conf.ErrorRenderer,
+			maxBodySize:   conf.MaxBodySize,
+		}
+
+		c.setHeader(headerCorrelationID, correlationID)
+		c.setHeader(headerBuildVersion, conf.BuildVersion)
+
+		c.AddNewRelicAttribute("functionName", conf.FunctionName)
+		c.AddNewRelicAttribute("route", r.RawPath)
+		c.AddNewRelicAttribute("correlationID", correlationID)
+		c.AddNewRelicAttribute("buildVersion", conf.BuildVersion)
+
+		err := callHandler(c.NewRelicTx, func() error { return h(c) })
+		if err != nil {
+			c.handleError(err)
+		}
+
+		return c.Response, nil
+	}
+}
+
+func FunctionURLHandlerWithNewRelic(h FunctionURLHandlerFunc, conf HandlerConfig) lambda.Handler {
+	return nrlambda.Wrap(FunctionURLHandler(h, conf), conf.NewRelicApp)
+}
+
+func (c *FunctionURLContext) Bind(v interface{}) error {
+	b, err := c.Body()
+	if err != nil {
+		return err
+	}
+
+	return bindJSON(b, v, c.validator)
+}
+
+// Body returns the raw request body, decoding it when it has been base64 encoded and decompressing it according to
+// the Content-Encoding header. ErrRequestBodyTooLarge is returned when the body is larger than the configured
+// MaxBodySize.
+func (c *FunctionURLContext) Body() ([]byte, error) {
+	return functionURLBody(c.Request, c.maxBodySize)
+}
+
+func (c *FunctionURLContext) JSON(statusCode int, body interface{}) error {
+	var b []byte
+	var err error
+	if body != nil {
+		b, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		c.setHeader("Content-Type", contentTypeJSON)
+	}
+	c.Response.StatusCode = statusCode
+	c.Response.Body = string(b)
+	c.Response.IsBase64Encoded = false
+	return nil
+}
+
+// SetCookie adds a cookie to the response, which is sent as a Set-Cookie header
+func (c *FunctionURLContext) SetCookie(cookie http.Cookie) {
+	c.Response.Cookies = append(c.Response.Cookies, cookie.String())
+}
+
+func (c *FunctionURLContext) AddNewRelicAttribute(key string, val interface{}) {
+	if c.NewRelicTx == nil {
+		return
+	}
+	if err := c.NewRelicTx.AddAttribute(key, val); err != nil {
+		c.Logger.Error().Msgf("failed to add attr '%s' to new relic tx: %+v", key, err)
+	}
+}
+
+func (c *FunctionURLContext) handleError(err error) {
+	statusCode, contentType, b := renderHandlerError(c.Logger, c.errorRenderer, err, c.CorrelationID)
+	c.setHeader("Content-Type", contentType)
+	c.Response.StatusCode = statusCode
+	c.Response.Body = string(b)
+	c.Response.IsBase64Encoded = false
+}
+
+// GetCookie retrieves the cookie with the given name from the request Cookies
+func (c *FunctionURLContext) GetCookie(name string) (http.Cookie, bool) {
+	return lookupCookie(c.Request.Cookies, name)
+}
+
+// GetHeader retrieves the header value by name in a case insensitive manner
+func (c *FunctionURLContext) GetHeader(name string) string {
+	return lookupHeader(c.Request.Headers, name)
+}
+
+func (c *FunctionURLContext) setHeader(key, value string) {
+	if c.Response.Headers == nil {
+		c.Response.Headers = make(map[string]string)
+	}
+	c.Response.Headers[key] = value
+}
+
+// FunctionURLStreamingContext is the context for requests to a Lambda Function URL using the RESPONSE_STREAM invoke
+// mode. The context is an io.Writer for the response body, and the StatusCode, Headers and Cookies are sent when the
+// body is first written to, so they must be set before then.
+type FunctionURLStreamingContext struct {
+	Context       context.Context
+	Request       events.LambdaFunctionURLRequest
+	Logger        zerolog.Logger
+	NewRelicTx    newrelic.Transaction
+	CorrelationID string
+	StatusCode    int
+	Headers       map[string]string
+	Cookies       []string
+	validator     Validator
+	errorRenderer ErrorRenderer
+	maxBodySize   int64
+	body          io.Writer
+	commit        func()
+	committed     bool
+}
+
+type FunctionURLStreamingHandlerFunc func(c *FunctionURLStreamingContext) error
+
+// FunctionURLStreamingHandler streams the response body written by the handler. The handler runs until it returns,
+// while the status code and headers are returned to the Lambda runtime as soon as the body is first written to.
+// Errors returned before then are rendered as usual, while errors returned after the body has been written to are
+// logged and end the response early.
+//
+// The handler must be started with lambda.Start rather than New Relic's nrlambda, which doesn't support streaming.
+func FunctionURLStreamingHandler(
+	h FunctionURLStreamingHandlerFunc,
+	conf HandlerConfig,
+) func(context.Context, events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+	h = chainMiddleware(h, conf.FunctionURLStreamingMiddleware)
+	return func(
+		ctx context.Context,
+		r events.LambdaFunctionURLRequest,
+	) (*events.LambdaFunctionURLStreamingResponse, error) {
+		correlationID := getCorrelationIDFunctionURL(r.Headers)
+
+		pr, pw := io.Pipe()
+		started := make(chan struct{})
+		res := &events.LambdaFunctionURLStreamingResponse{Body: pr}
+		c := &FunctionURLStreamingContext{
+			Context:       ContextWithCorrelationID(ctx, correlationID),
+			Request:       r,
+			Logger:        functionURLLogger(conf, r, correlationID),
+			NewRelicTx:    newrelic.FromContext(ctx),
+			CorrelationID: correlationID,
+			StatusCode:    http.StatusOK,
+			Headers:       make(map[string]string),
+			validator:     conf.Validator,
+			errorRenderer: conf.ErrorRenderer,
+			maxBodySize:   conf.MaxBodySize,
+			body:          pw,
+		}
+		// the runtime reads the response while the handler is still running, so it's given a copy of the status code,
+		// headers and cookies as they were when the response was committed
+		c.commit = func() {
+			res.StatusCode = c.StatusCode
+			res.Headers = make(map[string]string, len(c.Headers))
+			for k, v := range c.Headers {
+				res.Headers[k] = v
+			}
+			res.Cookies = append([]string(nil), c.Cookies...)
+			close(started)
+		}
+
+		c.Headers[headerCorrelationID] = correlationID
+		c.Headers[headerBuildVersion] = conf.BuildVersion
+
+		c.AddNewRelicAttribute("functionName", conf.FunctionName)
+		c.AddNewRelicAttribute("route", r.RawPath)
+		c.AddNewRelicAttribute("correlationID", correlationID)
+		c.AddNewRelicAttribute("buildVersion", conf.BuildVersion)
+
+		go func() {
+			err := callHandler(c.NewRelicTx, func() error { return h(c) })
+			_ = pw.CloseWithError(c.finish(err))
+		}()
+		<-started
+
+		return res, nil
+	}
+}
+
+// Write writes a chunk of the response body, first sending the status code and headers if they haven't been sent
+func (c *FunctionURLStreamingContext) Write(p []byte) (int, error) {
+	c.writeHeader()
+	return c.body.Write(p)
+}
+
+func (c *FunctionURLStreamingContext) Bind(v interface{}) error {
+	b, err := c.Body()
+	if err != nil {
+		return err
+	}
+
+	return bindJSON(b, v, c.validator)
+}
+
+// Body returns the raw request body, decoding it when it has been base64 encoded and decompressing it according to
+// the Content-Encoding header. ErrRequestBodyTooLarge is returned when the body is larger than the configured
+// MaxBodySize.
+func (c *FunctionURLStreamingContext) Body() ([]byte, error) {
+	return functionURLBody(c.Request, c.maxBodySize)
+}
+
+// SetCookie adds a cookie to the response, which is sent as a Set-Cookie header
+func (c *FunctionURLStreamingContext) SetCookie(cookie http.Cookie) {
+	c.Cookies = append(c.Cookies, cookie.String())
+}
+
+func (c *FunctionURLStreamingContext) AddNewRelicAttribute(key string, val interface{}) {
+	if c.NewRelicTx == nil {
+		return
+	}
+	if err := c.NewRelicTx.AddAttribute(key, val); err != nil {
+		c.Logger.Error().Msgf("failed to add attr '%s' to new relic tx: %+v", key, err)
+	}
+}
+
+// GetCookie retrieves the cookie with the given name from the request Cookies
+func (c *FunctionURLStreamingContext) GetCookie(name string) (http.Cookie, bool) {
+	return lookupCookie(c.Request.Cookies, name)
+}
+
+// GetHeader retrieves the header value by name in a case insensitive manner
+func (c *FunctionURLStreamingContext) GetHeader(name string) string {
+	return lookupHeader(c.Request.Headers, name)
+}
+
+func (c *FunctionURLStreamingContext) writeHeader() {
+	if c.committed {
+		return
+	}
+	c.committed = true
+	c.commit()
+}
+
+// finish renders the error returned by the handler when nothing has been written yet, and otherwise logs it and
+// returns it so that the response is ended early
+func (c *FunctionURLStreamingContext) finish(err error) error {
+	if err == nil {
+		c.writeHeader()
+		return nil
+	}
+
+	if c.committed {
+		logUnhandledError(c.Logger, err)
+		return err
+	}
+
+	statusCode, contentType, b := renderHandlerError(c.Logger, c.errorRenderer, err, c.CorrelationID)
+	c.StatusCode = statusCode
+	c.Headers["Content-Type"] = contentType
+	_, wErr := c.Write(b)
+	return wErr
+}
+
+func functionURLLogger(conf HandlerConfig, r events.LambdaFunctionURLRequest, correlationID string) zerolog.Logger {
+	return configureLogger(conf).
+		Str("route", r.RawPath).
+		Str("correlation_id", correlationID).
+		Logger()
+}
+
+func functionURLBody(r events.LambdaFunctionURLRequest, maxBodySize int64) ([]byte, error) {
+	b, err := decodeBody(r.Body, r.IsBase64Encoded)
+	if err != nil {
+		return nil, err
+	}
+	return decompressBody(b, lookupHeader(r.Headers, "Content-Encoding"), maxBodySize)
+}
+
+func getCorrelationIDFunctionURL(headers map[string]string) string {
+	correlationID := lookupHeader(headers, headerCorrelationID)
+	if correlationID != "" {
+		return correlationID
+	}
+	return uuid.New().String()
+}
@@ -0,0 +1,234 @@
+package g8_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/JSainsburyPLC/g8"
+)
+
+func TestFunctionURLHandler_SuccessResponse(t *testing.T) {
+	h := func(c *g8.FunctionURLContext) error {
+		var b body
+		if err := c.Bind(&b); err != nil {
+			return err
+		}
+		cookie, ok := c.GetCookie("session")
+		assert.True(t, ok)
+		assert.Equal(t, "abc", cookie.Value)
+
+		c.SetCookie(http.Cookie{Name: "theme", Value: "dark"})
+		return c.JSON(http.StatusCreated, b)
+	}
+
+	lh := g8.FunctionURLHandler(h, g8.HandlerConfig{BuildVersion: "1.0.0"})
+
+	res, err := lh(context.Background(), events.LambdaFunctionURLRequest{
+		RawPath: "/items",
+		Cookies: []string{"session=abc"},
+		Headers: map[string]string{"correlation-id": "abcdef"},
+		Body:    `{"name":"one","status":"ok"}`,
+	})
+
+	assert.Nil(t, err)
+	r := res.(events.LambdaFunctionURLResponse)
+	assert.Equal(t, http.StatusCreated, r.StatusCode)
+	assert.JSONEq(t, `{"name":"one","status":"ok"}`, r.Body)
+	assert.Equal(t, "application/json", r.Headers["Content-Type"])
+	assert.Equal(t, "abcdef", r.Headers["Correlation-Id"])
+	assert.Equal(t, "1.0.0", r.Headers["Build-Version"])
+	assert.Equal(t, []string{"theme=dark"}, r.Cookies)
+}
+
+func TestFunctionURLHandler_ErrorResponse(t *testing.T) {
+	var logBuf bytes.Buffer
+	lh := g8.FunctionURLHandler(func(c *g8.FunctionURLContext) error {
+		return errors.New("some error")
+	}, g8.HandlerConfig{Logger: zerolog.New(&logBuf)})
+
+	res, err := lh(context.Background(), events.LambdaFunctionURLRequest{})
+
+	assert.Nil(t, err)
+	r := res.(events.LambdaFunctionURLResponse)
+	assert.Equal(t, http.StatusInternalServerError, r.StatusCode)
+	assert.JSONEq(t, `{"code":"INTERNAL_SERVER_ERROR","detail":"Internal server error"}`, r.Body)
+	assert.True(t, containsLogMessage(logBuf.String(), "Unhandled error"))
+}
+
+func TestFunctionURLStreamingHandler(t *testing.T) {
+	written := make(chan struct{})
+	finish := make(chan struct{})
+	h := func(c *g8.FunctionURLStreamingContext) error {
+		c.Headers["Content-Type"] = "text/csv"
+		w := csv.NewWriter(c)
+		_ = w.Write([]string{"name", "price"})
+		w.Flush()
+		close(written)
+
+		<-finish
+		_ = w.Write([]string{"apple", "1.20"})
+		w.Flush()
+		return w.Error()
+	}
+
+	lh := g8.FunctionURLStreamingHandler(h, g8.HandlerConfig{BuildVersion: "1.0.0"})
+
+	res, err := lh(context.Background(), events.LambdaFunctionURLRequest{
+		Headers: map[string]string{"correlation-id": "abcdef"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, map[string]string{
+		"Content-Type":   "text/csv",
+		"Correlation-Id": "abcdef",
+		"Build-Version":  "1.0.0",
+	}, res.Headers)
+
+	// the response is returned while the handler is still writing the body
+	chunk := make([]byte, len("name,price\n"))
+	_, err = io.ReadFull(res.Body, chunk)
+	require.NoError(t, err)
+	<-written
+	assert.Equal(t, "name,price\n", string(chunk))
+
+	close(finish)
+	rest, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "apple,1.20\n", string(rest))
+}
+
+func TestFunctionURLStreamingHandler_ErrorBeforeWrite(t *testing.T) {
+	lh := g8.FunctionURLStreamingHandler(func(c *g8.FunctionURLStreamingContext) error {
+		return g8.ErrNotFound
+	}, g8.HandlerConfig{})
+
+	res, err := lh(context.Background(), events.LambdaFunctionURLRequest{})
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	assert.Equal(t, "application/json", res.Headers["Content-Type"])
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"code":"NOT_FOUND","detail":"Not found"}`, string(b))
+}
+
+func TestFunctionURLStreamingHandler_ErrorAfterWrite(t *testing.T) {
+	var logBuf bytes.Buffer
+	lh := g8.FunctionURLStreamingHandler(func(c *g8.FunctionURLStreamingContext) error {
+		if _, err := c.Write([]byte("name,price\n")); err != nil {
+			return err
+		}
+		return errors.New("export failed")
+	}, g8.HandlerConfig{Logger: zerolog.New(&logBuf)})
+
+	res, err := lh(context.Background(), events.LambdaFunctionURLRequest{})
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	b, err := io.ReadAll(res.Body)
+	assert.EqualError(t, err, "export failed")
+	assert.Equal(t, "name,price\n", string(b))
+	assert.True(t, containsLogMessage(logBuf.String(), "Unhandled error"))
+}
+
+func TestFunctionURLStreamingHandler_HeadersSetAfterWrite(t *testing.T) {
+	h := func(c *g8.FunctionURLStreamingContext) error {
+		if _, err := c.Write([]byte("name,price\n")); err != nil {
+			return err
+		}
+		c.Headers["X-Late"] = "1"
+		c.SetCookie(http.Cookie{Name: "late", Value: "1"})
+		return nil
+	}
+	mw := func(next g8.FunctionURLStreamingHandlerFunc) g8.FunctionURLStreamingHandlerFunc {
+		return func(c *g8.FunctionURLStreamingContext) error {
+			err := next(c)
+			c.Headers["X-Middleware"] = "1"
+			return err
+		}
+	}
+
+	lh := g8.FunctionURLStreamingHandler(h, g8.HandlerConfig{
+		FunctionURLStreamingMiddleware: []g8.FunctionURLStreamingMiddleware{mw},
+	})
+
+	res, err := lh(context.Background(), events.LambdaFunctionURLRequest{
+		Headers: map[string]string{"correlation-id": "abcdef"},
+	})
+	require.NoError(t, err)
+
+	// the runtime marshals the headers and cookies while the handler is still running
+	_, err = json.Marshal(res.Headers)
+	require.NoError(t, err)
+	_, err = json.Marshal(res.Cookies)
+	require.NoError(t, err)
+
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "name,price\n", string(b))
+	assert.NotContains(t, res.Headers, "X-Late")
+	assert.NotContains(t, res.Headers, "X-Middleware")
+	assert.Empty(t, res.Cookies)
+}
+
+func TestLambdaAdapter_FunctionURLStreaming(t *testing.T) {
+	l := g8.LambdaHandler{
+		Handler: func(ctx *g8.FunctionURLStreamingContext) error {
+			ctx.Headers["Content-Type"] = "text/csv"
+			w := csv.NewWriter(ctx)
+			for _, record := range [][]string{{"name", "price"}, {"apple", "1.20"}} {
+				if err := w.Write(record); err != nil {
+					return err
+				}
+				w.Flush()
+			}
+			return w.Error()
+		},
+		Method:      http.MethodGet,
+		PathPattern: "/exports",
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(g8.LambdaAdapter(l)))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/exports")
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []string{"chunked"}, res.TransferEncoding)
+	assert.Equal(t, "text/csv", res.Header.Get("Content-Type"))
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "name,price\napple,1.20\n", string(b))
+}
+
+func TestLambdaAdapter_FunctionURL(t *testing.T) {
+	l := g8.LambdaHandler{
+		Handler: func(ctx *g8.FunctionURLContext) error {
+			return ctx.JSON(http.StatusOK, body{Name: ctx.Request.QueryStringParameters["name"]})
+		},
+		Method:      http.MethodGet,
+		PathPattern: "/items",
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/items?name=one", nil)
+	g8.LambdaAdapter(l)(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"name":"one","status":""}`, w.Body.String())
+}
@@ -1,9 +1,15 @@
 package g8
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
 	"strings"
+	"time"
 
 	newrelic "github.com/newrelic/go-agent"
 	"github.com/rotisserie/eris"
@@ -22,6 +28,79 @@ type HandlerConfig struct {
 	BuildVersion string
 	Logger       zerolog.Logger
 	NewRelicApp  newrelic.Application
+
+	// Validator validates values after they are bound by APIGatewayProxyContext.Bind and SQSContext.Bind,
+	// e.g. TagValidator
+	Validator Validator
+
+	// ErrorRenderer renders errors returned by HTTP handlers, defaulting to JSONErrorRenderer
+	ErrorRenderer ErrorRenderer
+
+	// BinaryMediaTypes are the API Gateway binary media types, e.g. "image/*", used by LambdaAdapter to base64
+	// encode request bodies in the same way as API Gateway
+	BinaryMediaTypes []string
+
+	// Compression configures compression of APIGatewayProxyHandler response bodies, which is disabled by default
+	Compression CompressionConfig
+
+	// MaxBodySize is the maximum size in bytes of a decoded and decompressed request body read by
+	// APIGatewayProxyContext.Bind and Body. When it is zero uncompressed bodies have no limit, while compressed bodies
+	// are limited to 10 MiB once decompressed so that a small request can't expand to exhaust the function's memory.
+	MaxBodySize int64
+
+	// CORS configures the cross-origin resource sharing headers added to APIGatewayProxyHandler responses and
+	// the responses to preflight requests
+	CORS CORSConfig
+
+	// WebSocketSender sends data to the clients of WebSocketHandler connections, e.g. using the API Gateway
+	// Management API
+	WebSocketSender ConnectionSender
+
+	// WebSocketRouteSelectionField is the field of JSON messages used by NewHTTPHandler to select WebSocket routes,
+	// matching the API's $request.body.<field> route selection expression. It defaults to "action".
+	WebSocketRouteSelectionField string
+
+	// SQSConcurrency is the maximum number of records in a batch processed at the same time by SQSHandler and
+	// SQSBatchHandler, with records processed one at a time when it is zero. Each record's handler is timed in a New
+	// Relic segment.
+	SQSConcurrency int
+
+	// SQSFIFO processes the records of each FIFO queue message group in order, one at a time, while different groups
+	// are processed at the same time up to SQSConcurrency. The records following a failed record in its group aren't
+	// processed, so that SQSBatchHandler reports them as failures to be retried in order.
+	SQSFIFO bool
+
+	// SQSDeadlineMargin is how long before the Lambda deadline SQSHandler and SQSBatchHandler stop starting records,
+	// so that those not processed are reported before the invocation times out. It defaults to a tenth of the time
+	// left when the invocation starts, up to 5 seconds.
+	SQSDeadlineMargin time.Duration
+
+	// SQSCorrelationIDAttributes are the names of the SQS or SNS message attributes checked for the correlation ID
+	// of messages without an envelope, matched case insensitively. They default to "correlation_id",
+	// "correlation-id" and "correlationId".
+	SQSCorrelationIDAttributes []string
+
+	// SQSUnwrapBody replaces the body of SQS messages sent by an SNS subscription or an EventBridge rule with the
+	// published message or the event's detail, so that Bind reads them. The correlation ID and Meta are taken from
+	// the published message either way, and the raw body is kept as SQSContext.RawBody.
+	SQSUnwrapBody bool
+
+	// SQSIdempotency configures SQSHandler and SQSBatchHandler to skip messages which have already been processed
+	SQSIdempotency IdempotencyConfig
+
+	// Middleware for each handler type, see middleware.go
+	APIGatewayProxyMiddleware            []APIGatewayProxyMiddleware
+	APIGatewayV2HTTPMiddleware           []APIGatewayV2HTTPMiddleware
+	ALBMiddleware                        []ALBMiddleware
+	FunctionURLMiddleware                []FunctionURLMiddleware
+	FunctionURLStreamingMiddleware       []FunctionURLStreamingMiddleware
+	WebSocketMiddleware                  []WebSocketMiddleware
+	APIGatewayCustomAuthorizerMiddleware []APIGatewayCustomAuthorizerMiddleware
+	SQSMiddleware                        []SQSMiddleware
+	S3Middleware                         []S3Middleware
+	DynamoDbMiddleware                   []DynamoDbMiddleware
+	StepMiddleware                       []StepMiddleware
+	CloudWatchMiddleware                 []CloudWatchMiddleware
 }
 
 type LambdaResult interface{}
@@ -36,15 +115,81 @@ type Err struct {
 	Status int    `json:"-"`
 	Code   string `json:"code"`
 	Detail string `json:"detail"`
+	// Details are the optional field errors and meta data, kept behind a pointer so that Err values can still be
+	// compared with ==
+	Details *ErrDetails `json:"-"`
+}
+
+// ErrDetails are the field errors and meta data of an Err, written alongside its code and detail
+type ErrDetails struct {
+	Fields []FieldError           `json:"fields,omitempty"`
+	Meta   map[string]interface{} `json:"meta,omitempty"`
+}
+
+// FieldError describes why the value at a field path is invalid, e.g. "items[0].name"
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 func (err Err) Error() string {
 	errorParts := []string{
 		fmt.Sprintf("Code: %s; Status: %d; Detail: %s", err.Code, err.Status, err.Detail),
 	}
+	for _, f := range err.fields() {
+		errorParts = append(errorParts, fmt.Sprintf("Field: %s; Code: %s; Message: %s", f.Field, f.Code, f.Message))
+	}
 	return strings.Join(errorParts, "; ")
 }
 
+func (err Err) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Code   string                 `json:"code"`
+		Detail string                 `json:"detail"`
+		Fields []FieldError           `json:"fields,omitempty"`
+		Meta   map[string]interface{} `json:"meta,omitempty"`
+	}{err.Code, err.Detail, err.fields(), err.meta()})
+}
+
+// Is reports whether the target is an Err with the same status and code, e.g. errors.Is(err, g8.ErrInvalidBody)
+// matches an ErrInvalidBody with details
+func (err Err) Is(target error) bool {
+	switch t := target.(type) {
+	case Err:
+		return err.Status == t.Status && err.Code == t.Code
+	case *Err:
+		return t != nil && err.Status == t.Status && err.Code == t.Code
+	}
+	return false
+}
+
+// WithFields returns a copy of the Err with the field errors, leaving the Err it's called on unchanged
+func (err Err) WithFields(fields ...FieldError) Err {
+	err.Details = &ErrDetails{Fields: fields, Meta: err.meta()}
+	return err
+}
+
+// WithMeta returns a copy of the Err with the meta data, leaving the Err it's called on unchanged
+func (err Err) WithMeta(meta map[string]interface{}) Err {
+	err.Details = &ErrDetails{Fields: err.fields(), Meta: meta}
+	return err
+}
+
+func (err Err) fields() []FieldError {
+	if err.Details == nil {
+		return nil
+	}
+	return err.Details.Fields
+}
+
+func (err Err) meta() map[string]interface{} {
+	if err.Details == nil {
+		return nil
+	}
+	return err.Details.Meta
+}
+
 var ErrInternalServer = Err{
 	Status: http.StatusInternalServerError,
 	Code:   "INTERNAL_SERVER_ERROR",
@@ -57,6 +202,30 @@ var ErrInvalidBody = Err{
 	Detail: "Invalid request body",
 }
 
+var ErrRequestBodyTooLarge = Err{
+	Status: http.StatusRequestEntityTooLarge,
+	Code:   "REQUEST_BODY_TOO_LARGE",
+	Detail: "Request body too large",
+}
+
+var ErrUnsupportedContentEncoding = Err{
+	Status: http.StatusUnsupportedMediaType,
+	Code:   "UNSUPPORTED_CONTENT_ENCODING",
+	Detail: "Unsupported content encoding",
+}
+
+var ErrNotFound = Err{
+	Status: http.StatusNotFound,
+	Code:   "NOT_FOUND",
+	Detail: "Not found",
+}
+
+var ErrMethodNotAllowed = Err{
+	Status: http.StatusMethodNotAllowed,
+	Code:   "METHOD_NOT_ALLOWED",
+	Detail: "Method not allowed",
+}
+
 func ErrValidation(detail string) Err {
 	return Err{
 		Status: http.StatusBadRequest,
@@ -65,6 +234,121 @@ func ErrValidation(detail string) Err {
 	}
 }
 
+// ErrValidationFields is an ErrValidation listing every invalid field
+func ErrValidationFields(fields ...FieldError) Err {
+	details := make([]string, len(fields))
+	for i, f := range fields {
+		details[i] = fmt.Sprintf("%s: %s", f.Field, f.Message)
+	}
+	return ErrValidation(strings.Join(details, "; ")).WithFields(fields...)
+}
+
+// errInvalidBody describes why a request body could not be unmarshalled, with the offset of a syntax error or the
+// field with the wrong type. The detail is kept generic so that Go type names aren't exposed to clients.
+func errInvalidBody(err error) Err {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return ErrInvalidBody.WithMeta(map[string]interface{}{"offset": syntaxErr.Offset})
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "$"
+		}
+		return ErrInvalidBody.WithFields(FieldError{
+			Field:   field,
+			Code:    "INVALID_TYPE",
+			Message: fmt.Sprintf("must be %s but got %s", jsonTypeName(typeErr.Type), typeErr.Value),
+		}).WithMeta(map[string]interface{}{"offset": typeErr.Offset})
+	}
+	return ErrInvalidBody
+}
+
+// jsonTypeName returns the JSON name of the type a value is unmarshalled into, e.g. "object" for a struct
+func jsonTypeName(t reflect.Type) string {
+	if t == nil {
+		return "value"
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	}
+	return "value"
+}
+
+// bindJSON unmarshals the JSON into v, returning ErrInvalidBody when it's invalid, and validates it
+func bindJSON(b []byte, v interface{}, validator Validator) error {
+	if err := json.Unmarshal(b, v); err != nil {
+		return errInvalidBody(err)
+	}
+	return validate(validator, v)
+}
+
+// asErr finds the outermost Err in the error chain, unwrapping wrapped errors, e.g. from eris.Wrap or
+// fmt.Errorf("%w"), and joined errors. Both Err and *Err values are matched.
+func asErr(err error) (Err, bool) {
+	switch e := err.(type) {
+	case Err:
+		return e, true
+	case *Err:
+		if e != nil {
+			return *e, true
+		}
+	}
+
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		if next := u.Unwrap(); next != nil {
+			return asErr(next)
+		}
+	case interface{ Unwrap() []error }:
+		for _, next := range u.Unwrap() {
+			if e, ok := asErr(next); ok {
+				return e, true
+			}
+		}
+	}
+	return Err{}, false
+}
+
+// isErr reports whether the error is an Err itself rather than wrapping one
+func isErr(err error) bool {
+	switch err.(type) {
+	case Err, *Err:
+		return true
+	}
+	return false
+}
+
+// decodeBody decodes a request body which may have been base64 encoded by the event source
+func decodeBody(body string, isBase64Encoded bool) ([]byte, error) {
+	if !isBase64Encoded {
+		return []byte(body), nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		newErr := ErrInvalidBody
+		newErr.Detail = fmt.Sprintf("%s: invalid base64 encoding", ErrInvalidBody.Detail)
+		return nil, newErr
+	}
+	return b, nil
+}
+
 func configureLogger(conf HandlerConfig) zerolog.Context {
 	return conf.Logger.With().
 		Str("application", conf.AppName).
@@ -73,6 +357,26 @@ func configureLogger(conf HandlerConfig) zerolog.Context {
 		Str("build_version", conf.BuildVersion)
 }
 
+// callHandler calls the handler func, recovering a panic as an error with a stack trace so that it is handled in
+// the same way as a returned error. Recovered panics are reported to New Relic.
+func callHandler(txn newrelic.Transaction, fn func() error) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if rErr, ok := r.(error); ok {
+			err = eris.Wrap(rErr, "recovered panic")
+		} else {
+			err = eris.Errorf("recovered panic: %v", r)
+		}
+		if txn != nil {
+			_ = txn.NoticeError(err)
+		}
+	}()
+	return fn()
+}
+
 func logUnhandledError(logger zerolog.Logger, err error) {
 	logger.Error().
 		Fields(map[string]interface{}{
@@ -80,3 +384,27 @@ func logUnhandledError(logger zerolog.Logger, err error) {
 		}).
 		Msg("Unhandled error")
 }
+
+// logWrappedError logs the full chain of an error which wraps an Err, so that the context added when wrapping
+// isn't lost when the Err is written to the response
+func logWrappedError(logger zerolog.Logger, err error) {
+	logger.Warn().
+		Fields(map[string]interface{}{
+			"error": eris.ToJSON(err, true),
+		}).
+		Msg("Handled error")
+}
+
+type correlationIDKey struct{}
+
+// ContextWithCorrelationID returns a copy of the context carrying the correlation ID. The Context of every g8
+// context carries its CorrelationID, e.g. for the Publisher.
+func ContextWithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, correlationID)
+}
+
+// CorrelationIDFromContext returns the correlation ID carried by the context, or "" when it doesn't carry one
+func CorrelationIDFromContext(ctx context.Context) string {
+	correlationID, _ := ctx.Value(correlationIDKey{}).(string)
+	return correlationID
+}
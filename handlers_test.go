@@ -28,6 +28,63 @@ func TestError_Error(t *testing.T) {
 	}
 }
 
+func TestError_ErrorWithFields(t *testing.T) {
+	err := g8.ErrValidationFields(
+		g8.FieldError{Field: "name", Code: "REQUIRED", Message: "is required"},
+		g8.FieldError{Field: "items[0].quantity", Code: "TOO_SMALL", Message: "must be at least 1"},
+	)
+
+	assert.Equal(t, "Code: VALIDATION_ERROR; Status: 400; Detail: name: is required; items[0].quantity: must be at least 1; "+
+		"Field: name; Code: REQUIRED; Message: is required; "+
+		"Field: items[0].quantity; Code: TOO_SMALL; Message: must be at least 1", err.Error())
+}
+
+func TestError_Is(t *testing.T) {
+	err := fmt.Errorf("context: %w", g8.Err{
+		Status:  http.StatusBadRequest,
+		Code:    "INVALID_REQUEST_BODY",
+		Detail:  "Invalid request body",
+		Details: &g8.ErrDetails{Meta: map[string]interface{}{"offset": 10}},
+	})
+
+	assert.True(t, errors.Is(err, g8.ErrInvalidBody))
+	assert.True(t, errors.Is(err, &g8.ErrInvalidBody))
+	assert.False(t, errors.Is(err, g8.ErrInternalServer))
+}
+
+func TestError_Comparable(t *testing.T) {
+	var err error = g8.ErrNotFound
+	var withDetails error = g8.ErrInvalidBody.WithMeta(map[string]interface{}{"offset": 10})
+
+	assert.True(t, err == g8.ErrNotFound)
+	assert.NotPanics(t, func() {
+		assert.False(t, withDetails == g8.ErrInvalidBody.WithMeta(map[string]interface{}{"offset": 10}))
+	})
+	assert.Nil(t, g8.ErrInvalidBody.Details)
+}
+
+func TestAPIGatewayProxyHandler_G8ErrorFieldsAndMetaResponse(t *testing.T) {
+	h := func(c *g8.APIGatewayProxyContext) error {
+		return g8.ErrValidationFields(g8.FieldError{Field: "name", Code: "REQUIRED", Message: "is required"}).
+			WithMeta(map[string]interface{}{"docs": "https://example.com/docs"})
+	}
+
+	lh := g8.APIGatewayProxyHandler(h, g8.HandlerConfig{})
+
+	apitest.New().
+		Handler(adapter.GetHTTPHandlerWithContext(lh, "/", nil, nil)).
+		Get("/").
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Body(`{
+					"code": "VALIDATION_ERROR",
+					"detail": "name: is required",
+					"fields": [{"field": "name", "code": "REQUIRED", "message": "is required"}],
+					"meta": {"docs": "https://example.com/docs"}
+				}`).
+		End()
+}
+
 func TestAPIGatewayProxyHandler_UnhandledErrorResponseWithStackTrace(t *testing.T) {
 	h := func(c *g8.APIGatewayProxyContext) error {
 		return eris.Wrap(errors.New("external error"), "additional context")
@@ -1,16 +1,23 @@
 package g8
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	adapter "github.com/jfallis/lambda-proxy-http-adapter"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
 )
 
 const (
@@ -20,10 +27,14 @@ const (
 
 type LambdaHandlerEndpoints []LambdaHandler
 
+// LambdaHandler describes an endpoint served by NewHTTPHandler. Method may be MethodAny to match every method, and
+// PathPattern may end in a greedy {proxy+} segment, e.g. for serving a Router's Dispatch func.
 type LambdaHandler struct {
 	Handler     any
 	Method      string
 	PathPattern string
+	// Config is the handler config used by the deployed lambda, so that local responses match
+	Config HandlerConfig
 }
 
 // NewHTTPHandler creates a new HTTP server that listens on the given port.
@@ -31,7 +42,15 @@ func NewHTTPHandler(lambdaEndpoints LambdaHandlerEndpoints, portNumber int) {
 	fmt.Printf("\n%s %d\n\n", WelcomeMessage, portNumber)
 	r := chi.NewRouter()
 	for _, l := range lambdaEndpoints {
-		r.MethodFunc(l.Method, l.PathPattern, LambdaAdapter(l))
+		pattern := chiPathPattern(l.PathPattern)
+		if l.Method == "" || l.Method == MethodAny {
+			r.HandleFunc(pattern, LambdaAdapter(l))
+			continue
+		}
+		r.MethodFunc(l.Method, pattern, LambdaAdapter(l))
+		if l.Config.CORS.enabled() && l.Method != http.MethodOptions {
+			r.Options(pattern, corsPreflightHandler(l.Config.CORS))
+		}
 	}
 	if err := http.ListenAndServe(fmt.Sprintf(":%d", portNumber), r); err != nil {
 		panic(err)
@@ -40,39 +59,138 @@ func NewHTTPHandler(lambdaEndpoints LambdaHandlerEndpoints, portNumber int) {
 
 // LambdaAdapter converts a LambdaHandler into a http.HandlerFunc.
 func LambdaAdapter(l LambdaHandler) func(http.ResponseWriter, *http.Request) {
+	conns := &localConnections{conns: make(map[string]*localConnection)}
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch eventHandler := l.Handler.(type) {
+		case WebSocketRoutes:
+			fmt.Printf("%s %s \n", r.Method, r.URL.Path)
+			serveWebSocket(w, r, l, eventHandler, conns)
 		case func(ctx *APIGatewayProxyContext) error:
 			fmt.Printf("%s %s \n", r.Method, r.URL.Path)
 
+			cors := l.Config.CORS
+			origin := r.Header.Get("Origin")
+			if cors.enabled() && isPreflightRequest(r.Method, origin, r.Header.Get("Access-Control-Request-Method")) {
+				corsPreflightHandler(cors)(w, r)
+				return
+			}
+
 			buf := new(strings.Builder)
 			if _, err := io.Copy(buf, r.Body); err != nil {
 				panic(err)
 			}
 
+			request := adapter.APIGatewayProxyRequestAdaptor(r, buf.String(), l.PathPattern, nil, nil)
+			if isBinaryMediaType(r.Header.Get("Content-Type"), l.Config.BinaryMediaTypes) {
+				request.Body = base64.StdEncoding.EncodeToString([]byte(buf.String()))
+				request.IsBase64Encoded = true
+			}
 			ctx := &APIGatewayProxyContext{
-				Request: adapter.APIGatewayProxyRequestAdaptor(r, buf.String(), l.PathPattern, nil, nil),
+				Request:       request,
+				CorrelationID: getCorrelationIDAPIGW(request.Headers),
+				validator:     l.Config.Validator,
+				errorRenderer: l.Config.ErrorRenderer,
+				maxBodySize:   l.Config.MaxBodySize,
+			}
+			ctx.Context = ContextWithCorrelationID(r.Context(), ctx.CorrelationID)
+			if hasGreedySegment(l.PathPattern) {
+				if params, ok := matchPathPattern(parsePathPattern(l.PathPattern), r.URL.Path); ok {
+					ctx.Request.PathParameters = params
+				}
 			}
 
-			if eErr := eventHandler(ctx); eErr != nil {
+			h := chainMiddleware(APIGatewayProxyHandlerFunc(eventHandler), l.Config.APIGatewayProxyMiddleware)
+			if eErr := callHandler(nil, func() error { return h(ctx) }); eErr != nil {
 				fmt.Printf("%s %s\n", UnhandledErrMessage, eErr.Error())
-				resp, uErr := unhandledError(eErr)
-				if uErr != nil {
-					panic(uErr)
-				}
-				ctx.Response = resp
+				ctx.handleError(eErr)
+			}
+			applyCORSHeaders(&ctx.Response, cors, cors.responseHeaders(origin))
+			if cErr := compressResponse(&ctx.Response, r.Header.Get("Accept-Encoding"), l.Config.Compression); cErr != nil {
+				panic(cErr)
 			}
 
-			w.Header().Set("Content-Type", "application/json")
-			for k, v := range ctx.Response.Headers {
-				w.Header().Set(k, v)
+			localResponse{
+				StatusCode:        ctx.Response.StatusCode,
+				Headers:           ctx.Response.Headers,
+				MultiValueHeaders: ctx.Response.MultiValueHeaders,
+				Body:              ctx.Response.Body,
+				IsBase64Encoded:   ctx.Response.IsBase64Encoded,
+			}.write(w)
+		case func(ctx *APIGatewayV2HTTPContext) error:
+			fmt.Printf("%s %s \n", r.Method, r.URL.Path)
+
+			ctx := &APIGatewayV2HTTPContext{
+				Request:       apiGatewayV2HTTPRequest(r, l),
+				validator:     l.Config.Validator,
+				errorRenderer: l.Config.ErrorRenderer,
+				maxBodySize:   l.Config.MaxBodySize,
 			}
-			for k, v := range ctx.Response.MultiValueHeaders {
-				w.Header().Set(k, strings.Join(v, ","))
+			ctx.CorrelationID = getCorrelationIDAPIGWV2(ctx.Request.Headers)
+			ctx.Context = ContextWithCorrelationID(r.Context(), ctx.CorrelationID)
+
+			h := chainMiddleware(APIGatewayV2HTTPHandlerFunc(eventHandler), l.Config.APIGatewayV2HTTPMiddleware)
+			if eErr := callHandler(nil, func() error { return h(ctx) }); eErr != nil {
+				fmt.Printf("%s %s\n", UnhandledErrMessage, eErr.Error())
+				ctx.handleError(eErr)
 			}
-			w.WriteHeader(ctx.Response.StatusCode)
-			if _, wErr := w.Write([]byte(ctx.Response.Body)); wErr != nil {
-				panic(wErr)
+
+			localResponse{
+				StatusCode:        ctx.Response.StatusCode,
+				Headers:           ctx.Response.Headers,
+				MultiValueHeaders: ctx.Response.MultiValueHeaders,
+				Cookies:           ctx.Response.Cookies,
+				Body:              ctx.Response.Body,
+				IsBase64Encoded:   ctx.Response.IsBase64Encoded,
+			}.write(w)
+		case func(ctx *FunctionURLContext) error:
+			fmt.Printf("%s %s \n", r.Method, r.URL.Path)
+
+			ctx := &FunctionURLContext{
+				Request:       functionURLRequest(r, l),
+				validator:     l.Config.Validator,
+				errorRenderer: l.Config.ErrorRenderer,
+				maxBodySize:   l.Config.MaxBodySize,
+			}
+			ctx.CorrelationID = getCorrelationIDFunctionURL(ctx.Request.Headers)
+			ctx.Context = ContextWithCorrelationID(r.Context(), ctx.CorrelationID)
+
+			h := chainMiddleware(FunctionURLHandlerFunc(eventHandler), l.Config.FunctionURLMiddleware)
+			if eErr := callHandler(nil, func() error { return h(ctx) }); eErr != nil {
+				fmt.Printf("%s %s\n", UnhandledErrMessage, eErr.Error())
+				ctx.handleError(eErr)
+			}
+
+			localResponse{
+				StatusCode:      ctx.Response.StatusCode,
+				Headers:         ctx.Response.Headers,
+				Cookies:         ctx.Response.Cookies,
+				Body:            ctx.Response.Body,
+				IsBase64Encoded: ctx.Response.IsBase64Encoded,
+			}.write(w)
+		case func(ctx *FunctionURLStreamingContext) error:
+			fmt.Printf("%s %s \n", r.Method, r.URL.Path)
+
+			ctx := &FunctionURLStreamingContext{
+				Request:       functionURLRequest(r, l),
+				StatusCode:    http.StatusOK,
+				Headers:       make(map[string]string),
+				validator:     l.Config.Validator,
+				errorRenderer: l.Config.ErrorRenderer,
+				maxBodySize:   l.Config.MaxBodySize,
+				body:          flushWriter{w: w},
+			}
+			ctx.CorrelationID = getCorrelationIDFunctionURL(ctx.Request.Headers)
+			ctx.Context = ContextWithCorrelationID(r.Context(), ctx.CorrelationID)
+			// the status code and headers are written when the body is first written to, after which the response
+			// is sent with chunked transfer encoding
+			ctx.commit = func() {
+				localResponse{StatusCode: ctx.StatusCode, Headers: ctx.Headers, Cookies: ctx.Cookies}.writeHeader(w)
+			}
+
+			h := chainMiddleware(FunctionURLStreamingHandlerFunc(eventHandler), l.Config.FunctionURLStreamingMiddleware)
+			eErr := callHandler(nil, func() error { return h(ctx) })
+			if fErr := ctx.finish(eErr); fErr != nil {
+				fmt.Printf("%s %s\n", UnhandledErrMessage, fErr.Error())
 			}
 		default:
 			panic(fmt.Sprintf("unknown type: %T", l.Handler))
@@ -80,26 +198,339 @@ func LambdaAdapter(l LambdaHandler) func(http.ResponseWriter, *http.Request) {
 	}
 }
 
-// unhandledError returns an APIGatewayProxyResponse with the given error.
-func unhandledError(err error) (events.APIGatewayProxyResponse, error) {
-	var newErr Err
-	switch err := err.(type) {
-	case Err:
-		newErr = err
-	default:
-		newErr = ErrInternalServer
+// localResponse is a response returned by a handler served by LambdaAdapter
+type localResponse struct {
+	StatusCode        int
+	Headers           map[string]string
+	MultiValueHeaders map[string][]string
+	Cookies           []string
+	Body              string
+	IsBase64Encoded   bool
+}
+
+// write writes the response, decoding the body when it's base64 encoded. The Content-Type defaults to JSON.
+func (res localResponse) write(w http.ResponseWriter) {
+	body := []byte(res.Body)
+	if res.IsBase64Encoded {
+		b, err := base64.StdEncoding.DecodeString(res.Body)
+		if err != nil {
+			panic(err)
+		}
+		body = b
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	res.writeHeader(w)
+	if _, err := w.Write(body); err != nil {
+		panic(err)
+	}
+}
+
+// writeHeader writes the status code, headers and cookies of the response
+func (res localResponse) writeHeader(w http.ResponseWriter) {
+	for k, v := range res.Headers {
+		w.Header().Set(k, v)
 	}
+	for k, v := range res.MultiValueHeaders {
+		w.Header().Set(k, strings.Join(v, ","))
+	}
+	for _, c := range res.Cookies {
+		w.Header().Add("Set-Cookie", c)
+	}
+	w.WriteHeader(res.StatusCode)
+}
 
-	var r events.APIGatewayProxyResponse
-	b, err := json.Marshal(newErr)
+// apiGatewayV2HTTPRequest converts a http.Request into the 2.0 payload format event sent by an HTTP API
+func apiGatewayV2HTTPRequest(r *http.Request, l LambdaHandler) events.APIGatewayV2HTTPRequest {
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		return r, err
+		panic(err)
+	}
+
+	request := events.APIGatewayV2HTTPRequest{
+		Version:        "2.0",
+		RouteKey:       fmt.Sprintf("%s %s", r.Method, l.PathPattern),
+		RawPath:        r.URL.Path,
+		RawQueryString: r.URL.RawQuery,
+		Cookies:        splitCookies(r.Header.Values("Cookie")),
+		Headers:        make(map[string]string),
+		Body:           string(body),
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RouteKey:  fmt.Sprintf("%s %s", r.Method, l.PathPattern),
+			Stage:     "$default",
+			RequestID: uuid.New().String(),
+			Time:      time.Now().UTC().Format("02/Jan/2006:15:04:05 -0700"),
+			TimeEpoch: time.Now().UnixMilli(),
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Protocol:  r.Proto,
+				SourceIP:  r.RemoteAddr,
+				UserAgent: r.UserAgent(),
+			},
+		},
+	}
+	if l.Method == "" || l.Method == MethodAny {
+		request.RouteKey = fmt.Sprintf("%s %s", MethodAny, l.PathPattern)
+		request.RequestContext.RouteKey = request.RouteKey
 	}
 
-	r.Headers = make(map[string]string)
-	r.Headers["Content-Type"] = "application/json"
-	r.StatusCode = newErr.Status
-	r.Body = string(b)
+	for k, v := range r.Header {
+		if strings.EqualFold(k, "Cookie") {
+			continue
+		}
+		request.Headers[strings.ToLower(k)] = strings.Join(v, ",")
+	}
+	if query := r.URL.Query(); len(query) > 0 {
+		request.QueryStringParameters = make(map[string]string)
+		for k, v := range query {
+			request.QueryStringParameters[k] = strings.Join(v, ",")
+		}
+	}
+	if params, ok := matchPathPattern(parsePathPattern(l.PathPattern), r.URL.Path); ok && len(params) > 0 {
+		request.PathParameters = params
+	}
+	if isBinaryMediaType(r.Header.Get("Content-Type"), l.Config.BinaryMediaTypes) {
+		request.Body = base64.StdEncoding.EncodeToString(body)
+		request.IsBase64Encoded = true
+	}
+	return request
+}
 
-	return r, nil
+// serveWebSocket serves a WebSocket connection, invoking the routes for the connection, each message and the
+// disconnection in the same way as API Gateway
+func serveWebSocket(
+	w http.ResponseWriter,
+	r *http.Request,
+	l LambdaHandler,
+	routes WebSocketRoutes,
+	conns *localConnections,
+) {
+	h := chainMiddleware(routes.dispatch, l.Config.WebSocketMiddleware)
+	connectionID := uuid.New().String()
+	connectedAt := time.Now().UnixMilli()
+
+	invoke := func(request events.APIGatewayWebsocketProxyRequest) events.APIGatewayProxyResponse {
+		request.RequestContext.ConnectionID = connectionID
+		request.RequestContext.ConnectedAt = connectedAt
+		request.RequestContext.RequestID = uuid.New().String()
+		request.RequestContext.RequestTimeEpoch = time.Now().UnixMilli()
+		request.RequestContext.Stage = "local"
+		request.RequestContext.MessageDirection = "IN"
+
+		c := newWebSocketContext(r.Context(), request, l.Config, conns)
+		if eErr := callHandler(nil, func() error { return h(c) }); eErr != nil {
+			fmt.Printf("%s %s\n", UnhandledErrMessage, eErr.Error())
+			c.handleError(eErr)
+		}
+		return c.Response
+	}
+
+	connectRequest := events.APIGatewayWebsocketProxyRequest{
+		Headers:                         make(map[string]string),
+		MultiValueHeaders:               r.Header,
+		MultiValueQueryStringParameters: r.URL.Query(),
+		QueryStringParameters:           make(map[string]string),
+		RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+			RouteKey:  RouteConnect,
+			EventType: "CONNECT",
+		},
+	}
+	for k := range r.Header {
+		connectRequest.Headers[k] = r.Header.Get(k)
+	}
+	for k, v := range r.URL.Query() {
+		connectRequest.QueryStringParameters[k] = v[0]
+	}
+	if res := invoke(connectRequest); res.StatusCode >= http.StatusMultipleChoices {
+		localResponse{
+			StatusCode:        res.StatusCode,
+			Headers:           res.Headers,
+			MultiValueHeaders: res.MultiValueHeaders,
+			Body:              res.Body,
+			IsBase64Encoded:   res.IsBase64Encoded,
+		}.write(w)
+		return
+	}
+
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		fmt.Printf("%s %s\n", UnhandledErrMessage, err.Error())
+		return
+	}
+	conns.add(connectionID, conn)
+	defer func() {
+		conns.remove(connectionID)
+		_ = conn.Close()
+		invoke(events.APIGatewayWebsocketProxyRequest{
+			RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+				RouteKey:  RouteDisconnect,
+				EventType: "DISCONNECT",
+			},
+		})
+	}()
+
+	for {
+		_, msg, rErr := conn.ReadMessage()
+		if rErr != nil {
+			return
+		}
+
+		res := invoke(events.APIGatewayWebsocketProxyRequest{
+			Body: string(msg),
+			RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+				RouteKey:  webSocketRouteKey(msg, routes, l.Config.WebSocketRouteSelectionField),
+				EventType: "MESSAGE",
+			},
+		})
+		if res.Body != "" {
+			if pErr := conns.PostToConnection(r.Context(), connectionID, []byte(res.Body)); pErr != nil {
+				fmt.Printf("%s %s\n", UnhandledErrMessage, pErr.Error())
+			}
+		}
+	}
+}
+
+// webSocketRouteKey selects the route for a message from the value of the route selection field, using RouteDefault
+// when the message isn't JSON or there isn't a route for the value
+func webSocketRouteKey(msg []byte, routes WebSocketRoutes, field string) string {
+	if field == "" {
+		field = "action"
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(msg, &body); err != nil {
+		return RouteDefault
+	}
+	if routeKey, ok := body[field].(string); ok {
+		if _, registered := routes[routeKey]; registered {
+			return routeKey
+		}
+	}
+	return RouteDefault
+}
+
+// localConnections is the ConnectionSender for WebSocket connections served by NewHTTPHandler
+type localConnections struct {
+	mu    sync.Mutex
+	conns map[string]*localConnection
+}
+
+type localConnection struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func (l *localConnections) PostToConnection(_ context.Context, connectionID string, data []byte) error {
+	l.mu.Lock()
+	c, ok := l.conns[connectionID]
+	l.mu.Unlock()
+	if !ok {
+		return ErrConnectionGone
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (l *localConnections) add(connectionID string, conn *websocket.Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.conns[connectionID] = &localConnection{conn: conn}
+}
+
+func (l *localConnections) remove(connectionID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.conns, connectionID)
+}
+
+// functionURLRequest converts a http.Request into the event sent by a Lambda Function URL, which uses the same
+// format as the HTTP API 2.0 payload format
+func functionURLRequest(r *http.Request, l LambdaHandler) events.LambdaFunctionURLRequest {
+	request := apiGatewayV2HTTPRequest(r, l)
+	return events.LambdaFunctionURLRequest{
+		Version:               request.Version,
+		RawPath:               request.RawPath,
+		RawQueryString:        request.RawQueryString,
+		Cookies:               request.Cookies,
+		Headers:               request.Headers,
+		QueryStringParameters: request.QueryStringParameters,
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			RequestID: request.RequestContext.RequestID,
+			Time:      request.RequestContext.Time,
+			TimeEpoch: request.RequestContext.TimeEpoch,
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{
+				Method:    request.RequestContext.HTTP.Method,
+				Path:      request.RequestContext.HTTP.Path,
+				Protocol:  request.RequestContext.HTTP.Protocol,
+				SourceIP:  request.RequestContext.HTTP.SourceIP,
+				UserAgent: request.RequestContext.HTTP.UserAgent,
+			},
+		},
+		Body:            request.Body,
+		IsBase64Encoded: request.IsBase64Encoded,
+	}
+}
+
+// flushWriter flushes every write so that streamed responses are sent chunk by chunk
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
+
+// splitCookies splits Cookie headers into the individual cookies, as HTTP APIs do for the Cookies field
+func splitCookies(headers []string) []string {
+	var cookies []string
+	for _, h := range headers {
+		for _, c := range strings.Split(h, ";") {
+			if c = strings.TrimSpace(c); c != "" {
+				cookies = append(cookies, c)
+			}
+		}
+	}
+	return cookies
+}
+
+// corsPreflightHandler responds to preflight requests for endpoints served by NewHTTPHandler
+func corsPreflightHandler(conf CORSConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res := corsPreflight(conf, r.Header.Get("Origin"), r.Header.Get("Access-Control-Request-Headers"))
+		localResponse{StatusCode: res.StatusCode, Headers: res.Headers}.writeHeader(w)
+	}
+}
+
+// chiPathPattern converts an API Gateway path pattern into a chi one, replacing a greedy {proxy+} segment
+// with a chi wildcard.
+func chiPathPattern(pattern string) string {
+	parts := strings.Split(pattern, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "+}") {
+			return strings.Join(append(parts[:i], "*"), "/")
+		}
+	}
+	return pattern
+}
+
+// isBinaryMediaType reports whether the content type matches one of the binary media types, which may contain
+// wildcards, e.g. "image/*" or "*/*"
+func isBinaryMediaType(contentType string, binaryMediaTypes []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, b := range binaryMediaTypes {
+		if mediaTypeMatches(strings.ToLower(b), mediaType) {
+			return true
+		}
+	}
+	return false
 }
@@ -128,3 +128,154 @@ func TestLambdaAdapter_generic_error(t *testing.T) {
 	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
 	assert.Equal(t, `{"code":"INTERNAL_SERVER_ERROR","detail":"Internal server error"}`, w.Body.String())
 }
+
+func TestLambdaAdapter_panic(t *testing.T) {
+	l := g8.LambdaHandler{
+		Handler: func(ctx *g8.APIGatewayProxyContext) error {
+			panic("something went badly wrong")
+		},
+		Method:      http.MethodGet,
+		PathPattern: "/test/url/path",
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/test/url/path", nil)
+	g8.LambdaAdapter(l)(w, r)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, `{"code":"INTERNAL_SERVER_ERROR","detail":"Internal server error"}`, w.Body.String())
+}
+
+func TestLambdaAdapter_wrapped_g8_error(t *testing.T) {
+	l := g8.LambdaHandler{
+		Handler: func(ctx *g8.APIGatewayProxyContext) error {
+			return fmt.Errorf("failed to get user: %w", g8.ErrValidation("invalid id"))
+		},
+		Method:      http.MethodGet,
+		PathPattern: "/test/url/path",
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/test/url/path", nil)
+	g8.LambdaAdapter(l)(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"code":"VALIDATION_ERROR","detail":"invalid id"}`, w.Body.String())
+}
+
+func TestLambdaAdapter_binary(t *testing.T) {
+	l := g8.LambdaHandler{
+		Handler: func(ctx *g8.APIGatewayProxyContext) error {
+			assert.True(t, ctx.Request.IsBase64Encoded)
+			b, err := ctx.Body()
+			if err != nil {
+				return err
+			}
+			return ctx.Blob(http.StatusOK, "image/png", append(b, 0xff))
+		},
+		Method:      http.MethodPost,
+		PathPattern: "/images",
+		Config: g8.HandlerConfig{
+			BinaryMediaTypes: []string{"image/*"},
+		},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/images", strings.NewReader("\x89PNG"))
+	r.Header.Set("Content-Type", "image/png")
+	g8.LambdaAdapter(l)(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, []byte("\x89PNG\xff"), w.Body.Bytes())
+}
+
+func TestLambdaAdapter_not_binary(t *testing.T) {
+	l := g8.LambdaHandler{
+		Handler: func(ctx *g8.APIGatewayProxyContext) error {
+			assert.False(t, ctx.Request.IsBase64Encoded)
+			return ctx.String(http.StatusOK, ctx.Request.Body)
+		},
+		Method:      http.MethodPost,
+		PathPattern: "/images",
+		Config: g8.HandlerConfig{
+			BinaryMediaTypes: []string{"image/*"},
+		},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/images", strings.NewReader(`{"key":"value"}`))
+	r.Header.Set("Content-Type", "application/json")
+	g8.LambdaAdapter(l)(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"key":"value"}`, w.Body.String())
+}
+
+func TestLambdaAdapter_Middleware(t *testing.T) {
+	tests := map[string]g8.LambdaHandler{
+		"api gateway proxy": {
+			Handler: func(c *g8.APIGatewayProxyContext) error {
+				return c.JSON(http.StatusOK, nil)
+			},
+			Config: g8.HandlerConfig{
+				APIGatewayProxyMiddleware: []g8.APIGatewayProxyMiddleware{
+					func(g8.APIGatewayProxyHandlerFunc) g8.APIGatewayProxyHandlerFunc {
+						return func(*g8.APIGatewayProxyContext) error { return g8.ErrNotFound }
+					},
+				},
+			},
+		},
+		"api gateway v2 http": {
+			Handler: func(c *g8.APIGatewayV2HTTPContext) error {
+				return c.JSON(http.StatusOK, nil)
+			},
+			Config: g8.HandlerConfig{
+				APIGatewayV2HTTPMiddleware: []g8.APIGatewayV2HTTPMiddleware{
+					func(g8.APIGatewayV2HTTPHandlerFunc) g8.APIGatewayV2HTTPHandlerFunc {
+						return func(*g8.APIGatewayV2HTTPContext) error { return g8.ErrNotFound }
+					},
+				},
+			},
+		},
+		"function url": {
+			Handler: func(c *g8.FunctionURLContext) error {
+				return c.JSON(http.StatusOK, nil)
+			},
+			Config: g8.HandlerConfig{
+				FunctionURLMiddleware: []g8.FunctionURLMiddleware{
+					func(g8.FunctionURLHandlerFunc) g8.FunctionURLHandlerFunc {
+						return func(*g8.FunctionURLContext) error { return g8.ErrNotFound }
+					},
+				},
+			},
+		},
+		"function url streaming": {
+			Handler: func(c *g8.FunctionURLStreamingContext) error {
+				_, err := c.Write([]byte("ok"))
+				return err
+			},
+			Config: g8.HandlerConfig{
+				FunctionURLStreamingMiddleware: []g8.FunctionURLStreamingMiddleware{
+					func(g8.FunctionURLStreamingHandlerFunc) g8.FunctionURLStreamingHandlerFunc {
+						return func(*g8.FunctionURLStreamingContext) error { return g8.ErrNotFound }
+					},
+				},
+			},
+		},
+	}
+
+	for name, l := range tests {
+		t.Run(name, func(t *testing.T) {
+			l.Method = http.MethodGet
+			l.PathPattern = "/test"
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/test", nil)
+			g8.LambdaAdapter(l)(w, r)
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+			assert.Equal(t, `{"code":"NOT_FOUND","detail":"Not found"}`, w.Body.String())
+		})
+	}
+}
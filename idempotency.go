@@ -0,0 +1,189 @@
+package g8
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rotisserie/eris"
+)
+
+// IdempotencyStatus is the status of an IdempotencyRecord
+type IdempotencyStatus string
+
+const (
+	IdempotencyInProgress IdempotencyStatus = "IN_PROGRESS"
+	IdempotencyCompleted  IdempotencyStatus = "COMPLETED"
+)
+
+var ErrIdempotencyInProgress = Err{
+	Status: http.StatusConflict,
+	Code:   "IDEMPOTENCY_IN_PROGRESS",
+	Detail: "Message is already being processed",
+}
+
+// IdempotencyRecord records that a message with the key is being processed, or has been processed along with the
+// result set with SQSContext.SetIdempotencyResult
+type IdempotencyRecord struct {
+	Key       string
+	Status    IdempotencyStatus
+	Result    []byte
+	ExpiresAt time.Time
+}
+
+// IdempotencyStore stores IdempotencyRecords, ignoring records which have expired
+type IdempotencyStore interface {
+	// Lock stores the in-progress record unless an unexpired record exists for its key, in which case the existing
+	// record is returned and nothing is stored
+	Lock(ctx context.Context, record IdempotencyRecord) (*IdempotencyRecord, error)
+	// Put stores the record, replacing any existing record for its key
+	Put(ctx context.Context, record IdempotencyRecord) error
+	// Delete removes the record for the key
+	Delete(ctx context.Context, key string) error
+}
+
+// IdempotencyConfig configures SQS handlers to process each message only once, skipping messages whose key has
+// already been processed. Idempotency is enabled when the Store is set.
+type IdempotencyConfig struct {
+	Store IdempotencyStore
+
+	// Key returns the idempotency key of the message, defaulting to its message ID
+	Key func(c *SQSContext) (string, error)
+
+	// TTL is how long a processed message's key is kept, defaulting to 24 hours
+	TTL time.Duration
+
+	// LockMargin is added to the Lambda deadline to get when a message's key is unlocked if it's still being
+	// processed, e.g. when the function crashed or timed out, so that the next delivery of the message can process it.
+	// It defaults to 10 seconds.
+	LockMargin time.Duration
+
+	// OnDuplicate is called instead of the handler when the message has already been processed, e.g. to use the
+	// cached result. Duplicates are skipped when it isn't set.
+	OnDuplicate func(c *SQSContext, record IdempotencyRecord) error
+}
+
+// wrap returns the handler processing each message only once. While the handler runs the message's key is locked, and
+// other deliveries of it fail with ErrIdempotencyInProgress so that they're retried. The key is unlocked when the
+// handler fails so that the message can be retried, and otherwise stored as completed until the TTL expires.
+func (conf IdempotencyConfig) wrap(h SQSHandlerFunc) SQSHandlerFunc {
+	if conf.Store == nil {
+		return h
+	}
+	return func(c *SQSContext) error {
+		key := c.Message.MessageId
+		if conf.Key != nil {
+			var err error
+			if key, err = conf.Key(c); err != nil {
+				return err
+			}
+		}
+		logger := c.Logger.With().Str("idempotency_key", key).Logger()
+
+		existing, err := conf.Store.Lock(c.Context, IdempotencyRecord{
+			Key:       key,
+			Status:    IdempotencyInProgress,
+			ExpiresAt: conf.lockExpiry(c.Context),
+		})
+		if err != nil {
+			return eris.Wrap(err, "failed to lock idempotency key")
+		}
+		if existing != nil {
+			if existing.Status != IdempotencyCompleted {
+				return ErrIdempotencyInProgress
+			}
+			logger.Info().Msg("Skipping duplicate SQS message")
+			if conf.OnDuplicate != nil {
+				return conf.OnDuplicate(c, *existing)
+			}
+			return nil
+		}
+
+		if err := callHandler(c.NewRelicTx, func() error { return h(c) }); err != nil {
+			if dErr := conf.Store.Delete(c.Context, key); dErr != nil {
+				logger.Error().Err(dErr).Msg("failed to unlock idempotency key")
+			}
+			return err
+		}
+
+		// the message has been processed, so it mustn't be retried when the record can't be stored
+		err = conf.Store.Put(c.Context, IdempotencyRecord{
+			Key:       key,
+			Status:    IdempotencyCompleted,
+			Result:    c.idempotencyResult,
+			ExpiresAt: time.Now().Add(conf.ttl()),
+		})
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to store completed idempotency record")
+		}
+		return nil
+	}
+}
+
+func (conf IdempotencyConfig) ttl() time.Duration {
+	if conf.TTL > 0 {
+		return conf.TTL
+	}
+	return 24 * time.Hour
+}
+
+// lockExpiry returns the time the lock expires, the margin after the context's deadline, or after the maximum Lambda
+// timeout of 15 minutes when it doesn't have one
+func (conf IdempotencyConfig) lockExpiry(ctx context.Context) time.Time {
+	margin := conf.LockMargin
+	if margin <= 0 {
+		margin = 10 * time.Second
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(15 * time.Minute)
+	}
+	return deadline.Add(margin)
+}
+
+// SetIdempotencyResult sets the result, marshalled as JSON, which is stored with the completed IdempotencyRecord
+// when idempotency is enabled
+func (c *SQSContext) SetIdempotencyResult(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.idempotencyResult = b
+	return nil
+}
+
+// InMemoryIdempotencyStore is an IdempotencyStore which keeps records in memory, e.g. for tests
+type InMemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+}
+
+func NewInMemoryIdempotencyStore() *InMemoryIdempotencyStore {
+	return &InMemoryIdempotencyStore{records: make(map[string]IdempotencyRecord)}
+}
+
+func (s *InMemoryIdempotencyStore) Lock(_ context.Context, record IdempotencyRecord) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.records[record.Key]; ok && time.Now().Before(existing.ExpiresAt) {
+		return &existing, nil
+	}
+	s.records[record.Key] = record
+	return nil, nil
+}
+
+func (s *InMemoryIdempotencyStore) Put(_ context.Context, record IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[record.Key] = record
+	return nil
+}
+
+func (s *InMemoryIdempotencyStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
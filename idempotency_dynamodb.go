@@ -0,0 +1,124 @@
+package g8
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/rotisserie/eris"
+)
+
+// DynamoDBAPI is the subset of the DynamoDB client used by DynamoDBIdempotencyStore, implemented by *dynamodb.Client
+type DynamoDBAPI interface {
+	PutItem(
+		ctx context.Context,
+		in *dynamodb.PutItemInput,
+		optFns ...func(*dynamodb.Options),
+	) (*dynamodb.PutItemOutput, error)
+	GetItem(
+		ctx context.Context,
+		in *dynamodb.GetItemInput,
+		optFns ...func(*dynamodb.Options),
+	) (*dynamodb.GetItemOutput, error)
+	DeleteItem(
+		ctx context.Context,
+		in *dynamodb.DeleteItemInput,
+		optFns ...func(*dynamodb.Options),
+	) (*dynamodb.DeleteItemOutput, error)
+}
+
+// DynamoDBIdempotencyStore is an IdempotencyStore backed by a DynamoDB table, or any API compatible with it such as
+// DynamoDB Local. The table's partition key must be the string attribute "id", and the number attribute "expiration"
+// can be enabled as the table's TTL attribute so that expired records are deleted.
+type DynamoDBIdempotencyStore struct {
+	Client    DynamoDBAPI
+	TableName string
+}
+
+func (s *DynamoDBIdempotencyStore) Lock(ctx context.Context, record IdempotencyRecord) (*IdempotencyRecord, error) {
+	_, err := s.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                aws.String(s.TableName),
+		Item:                     dynamoDBItem(record),
+		ConditionExpression:      aws.String("attribute_not_exists(#id) OR #expiration < :now"),
+		ExpressionAttributeNames: map[string]string{"#id": "id", "#expiration": "expiration"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Unix(), 10)},
+		},
+	})
+	var condErr *types.ConditionalCheckFailedException
+	switch {
+	case err == nil:
+		return nil, nil
+	case !errors.As(err, &condErr):
+		return nil, eris.Wrap(err, "failed to put idempotency record")
+	}
+
+	out, err := s.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.TableName),
+		Key:            dynamoDBKey(record.Key),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to get idempotency record")
+	}
+	if out.Item == nil {
+		// the record was deleted after the put failed, so it's still being processed or has just failed
+		return &IdempotencyRecord{Key: record.Key, Status: IdempotencyInProgress}, nil
+	}
+	return parseDynamoDBItem(out.Item)
+}
+
+func (s *DynamoDBIdempotencyStore) Put(ctx context.Context, record IdempotencyRecord) error {
+	_, err := s.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.TableName),
+		Item:      dynamoDBItem(record),
+	})
+	return eris.Wrap(err, "failed to put idempotency record")
+}
+
+func (s *DynamoDBIdempotencyStore) Delete(ctx context.Context, key string) error {
+	_, err := s.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.TableName),
+		Key:       dynamoDBKey(key),
+	})
+	return eris.Wrap(err, "failed to delete idempotency record")
+}
+
+func dynamoDBKey(key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: key}}
+}
+
+func dynamoDBItem(record IdempotencyRecord) map[string]types.AttributeValue {
+	item := dynamoDBKey(record.Key)
+	item["status"] = &types.AttributeValueMemberS{Value: string(record.Status)}
+	item["expiration"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(record.ExpiresAt.Unix(), 10)}
+	if record.Result != nil {
+		item["result"] = &types.AttributeValueMemberB{Value: record.Result}
+	}
+	return item
+}
+
+func parseDynamoDBItem(item map[string]types.AttributeValue) (*IdempotencyRecord, error) {
+	var record IdempotencyRecord
+	if v, ok := item["id"].(*types.AttributeValueMemberS); ok {
+		record.Key = v.Value
+	}
+	if v, ok := item["status"].(*types.AttributeValueMemberS); ok {
+		record.Status = IdempotencyStatus(v.Value)
+	}
+	if v, ok := item["result"].(*types.AttributeValueMemberB); ok {
+		record.Result = v.Value
+	}
+	if v, ok := item["expiration"].(*types.AttributeValueMemberN); ok {
+		expiration, err := strconv.ParseInt(v.Value, 10, 64)
+		if err != nil {
+			return nil, eris.Wrap(err, "invalid idempotency record expiration")
+		}
+		record.ExpiresAt = time.Unix(expiration, 0)
+	}
+	return &record, nil
+}
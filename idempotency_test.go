@@ -0,0 +1,341 @@
+package g8_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/JSainsburyPLC/g8"
+)
+
+func TestSQSIdempotency_SkipsDuplicates(t *testing.T) {
+	timesCalled := 0
+	var duplicate g8.IdempotencyRecord
+	h := g8.SQSHandler(func(c *g8.SQSContext) error {
+		timesCalled++
+		return c.SetIdempotencyResult(map[string]string{"payment_id": "pay-1"})
+	}, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+		SQSIdempotency: g8.IdempotencyConfig{
+			Store: g8.NewInMemoryIdempotencyStore(),
+			OnDuplicate: func(c *g8.SQSContext, record g8.IdempotencyRecord) error {
+				duplicate = record
+				return nil
+			},
+		},
+	})
+
+	e := events.SQSEvent{Records: []events.SQSMessage{{MessageId: "1", Body: `{}`}}}
+	require.NoError(t, h(context.Background(), e))
+	require.NoError(t, h(context.Background(), e))
+
+	assert.Equal(t, 1, timesCalled)
+	assert.Equal(t, "1", duplicate.Key)
+	assert.Equal(t, g8.IdempotencyCompleted, duplicate.Status)
+	assert.JSONEq(t, `{"payment_id":"pay-1"}`, string(duplicate.Result))
+}
+
+func TestSQSIdempotency_KeyFunc(t *testing.T) {
+	var processed []string
+	h := g8.SQSHandler(func(c *g8.SQSContext) error {
+		processed = append(processed, c.Message.MessageId)
+		return nil
+	}, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+		SQSIdempotency: g8.IdempotencyConfig{
+			Store: g8.NewInMemoryIdempotencyStore(),
+			Key: func(c *g8.SQSContext) (string, error) {
+				var order struct {
+					OrderID string `json:"order_id"`
+				}
+				err := json.Unmarshal([]byte(c.Message.Body), &order)
+				return order.OrderID, err
+			},
+		},
+	})
+
+	err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "1", Body: `{"order_id": "a"}`},
+		{MessageId: "2", Body: `{"order_id": "a"}`},
+		{MessageId: "3", Body: `{"order_id": "b"}`},
+	}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1", "3"}, processed)
+}
+
+func TestSQSIdempotency_FailureIsRetried(t *testing.T) {
+	timesCalled := 0
+	h := g8.SQSHandler(func(c *g8.SQSContext) error {
+		timesCalled++
+		if timesCalled == 1 {
+			panic("boom")
+		}
+		return nil
+	}, g8.HandlerConfig{
+		Logger:         zerolog.New(io.Discard),
+		SQSIdempotency: g8.IdempotencyConfig{Store: g8.NewInMemoryIdempotencyStore()},
+	})
+
+	e := events.SQSEvent{Records: []events.SQSMessage{{MessageId: "1", Body: `{}`}}}
+	assert.Error(t, h(context.Background(), e))
+	assert.NoError(t, h(context.Background(), e))
+	assert.NoError(t, h(context.Background(), e))
+	assert.Equal(t, 2, timesCalled)
+}
+
+func TestSQSIdempotency_InProgress(t *testing.T) {
+	store := g8.NewInMemoryIdempotencyStore()
+	_, err := store.Lock(context.Background(), g8.IdempotencyRecord{
+		Key:       "1",
+		Status:    g8.IdempotencyInProgress,
+		ExpiresAt: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	timesCalled := 0
+	h := g8.SQSBatchHandler(func(c *g8.SQSContext) error {
+		timesCalled++
+		return nil
+	}, g8.HandlerConfig{
+		Logger:         zerolog.New(io.Discard),
+		SQSIdempotency: g8.IdempotencyConfig{Store: store},
+	})
+
+	res, err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "1", Body: `{}`},
+		{MessageId: "2", Body: `{}`},
+	}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, timesCalled)
+	assert.Equal(t, []events.SQSBatchItemFailure{{ItemIdentifier: "1"}}, res.BatchItemFailures)
+}
+
+type lockRecordingStore struct {
+	g8.IdempotencyStore
+	locks []g8.IdempotencyRecord
+}
+
+func (s *lockRecordingStore) Lock(ctx context.Context, record g8.IdempotencyRecord) (*g8.IdempotencyRecord, error) {
+	s.locks = append(s.locks, record)
+	return s.IdempotencyStore.Lock(ctx, record)
+}
+
+func TestSQSIdempotency_LockExpiresAfterDeadline(t *testing.T) {
+	deadline := time.Now().Add(30 * time.Second)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	store := &lockRecordingStore{IdempotencyStore: g8.NewInMemoryIdempotencyStore()}
+	h := g8.SQSHandler(func(c *g8.SQSContext) error {
+		return nil
+	}, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+		SQSIdempotency: g8.IdempotencyConfig{
+			Store:      store,
+			LockMargin: 5 * time.Second,
+		},
+	})
+
+	require.NoError(t, h(ctx, events.SQSEvent{Records: []events.SQSMessage{{MessageId: "1", Body: `{}`}}}))
+
+	require.Len(t, store.locks, 1)
+	assert.True(t, deadline.Add(5*time.Second).Equal(store.locks[0].ExpiresAt))
+}
+
+func TestInMemoryIdempotencyStore(t *testing.T) {
+	testIdempotencyStore(t, g8.NewInMemoryIdempotencyStore())
+}
+
+// TestDynamoDBIdempotencyStore runs against DynamoDB Local, e.g.
+// docker run -p 8000:8000 amazon/dynamodb-local && G8_DYNAMODB_ENDPOINT=http://localhost:8000 go test ./...
+func TestDynamoDBIdempotencyStore(t *testing.T) {
+	endpoint := os.Getenv("G8_DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("G8_DYNAMODB_ENDPOINT not set")
+	}
+
+	ctx := context.Background()
+	client := dynamodb.New(dynamodb.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(endpoint),
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "local", SecretAccessKey: "local"}, nil
+		}),
+	})
+	tableName := fmt.Sprintf("g8-idempotency-%d", time.Now().UnixNano())
+	_, err := client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("id"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema:   []types.KeySchemaElement{{AttributeName: aws.String("id"), KeyType: types.KeyTypeHash}},
+		BillingMode: types.BillingModePayPerRequest,
+	})
+	require.NoError(t, err)
+	defer func() {
+		_, _ = client.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: aws.String(tableName)})
+	}()
+
+	testIdempotencyStore(t, &g8.DynamoDBIdempotencyStore{Client: client, TableName: tableName})
+}
+
+// fakeDynamoDB implements the DynamoDB API used by DynamoDBIdempotencyStore, evaluating the condition expression
+// of its conditional put
+type fakeDynamoDB struct {
+	items map[string]map[string]types.AttributeValue
+}
+
+func (f *fakeDynamoDB) PutItem(
+	_ context.Context,
+	in *dynamodb.PutItemInput,
+	_ ...func(*dynamodb.Options),
+) (*dynamodb.PutItemOutput, error) {
+	id := in.Item["id"].(*types.AttributeValueMemberS).Value
+	if in.ConditionExpression != nil {
+		if aws.ToString(in.ConditionExpression) != "attribute_not_exists(#id) OR #expiration < :now" {
+			return nil, fmt.Errorf("unexpected condition %s", aws.ToString(in.ConditionExpression))
+		}
+		if existing, ok := f.items[id]; ok {
+			expiration := existing[in.ExpressionAttributeNames["#expiration"]].(*types.AttributeValueMemberN).Value
+			now := in.ExpressionAttributeValues[":now"].(*types.AttributeValueMemberN).Value
+			if !(parseNumber(expiration) < parseNumber(now)) {
+				return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+			}
+		}
+	}
+	f.items[id] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) GetItem(
+	_ context.Context,
+	in *dynamodb.GetItemInput,
+	_ ...func(*dynamodb.Options),
+) (*dynamodb.GetItemOutput, error) {
+	if !aws.ToBool(in.ConsistentRead) {
+		return nil, fmt.Errorf("expected a consistent read")
+	}
+	return &dynamodb.GetItemOutput{Item: f.items[in.Key["id"].(*types.AttributeValueMemberS).Value]}, nil
+}
+
+func (f *fakeDynamoDB) DeleteItem(
+	_ context.Context,
+	in *dynamodb.DeleteItemInput,
+	_ ...func(*dynamodb.Options),
+) (*dynamodb.DeleteItemOutput, error) {
+	delete(f.items, in.Key["id"].(*types.AttributeValueMemberS).Value)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func parseNumber(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+func TestDynamoDBIdempotencyStore_FakeClient(t *testing.T) {
+	client := &fakeDynamoDB{items: make(map[string]map[string]types.AttributeValue)}
+	store := &g8.DynamoDBIdempotencyStore{Client: client, TableName: "idempotency"}
+
+	testIdempotencyStore(t, store)
+
+	// records are stored with the attributes documented for the table
+	expiresAt := time.Unix(1700000000, 0)
+	require.NoError(t, store.Put(context.Background(), g8.IdempotencyRecord{
+		Key:       "key-3",
+		Status:    g8.IdempotencyCompleted,
+		Result:    []byte(`{}`),
+		ExpiresAt: expiresAt,
+	}))
+	assert.Equal(t, map[string]types.AttributeValue{
+		"id":         &types.AttributeValueMemberS{Value: "key-3"},
+		"status":     &types.AttributeValueMemberS{Value: "COMPLETED"},
+		"result":     &types.AttributeValueMemberB{Value: []byte(`{}`)},
+		"expiration": &types.AttributeValueMemberN{Value: "1700000000"},
+	}, client.items["key-3"])
+}
+
+func TestDynamoDBIdempotencyStore_InvalidExpiration(t *testing.T) {
+	client := &fakeDynamoDB{items: map[string]map[string]types.AttributeValue{
+		"key-1": {
+			"id":         &types.AttributeValueMemberS{Value: "key-1"},
+			"status":     &types.AttributeValueMemberS{Value: "COMPLETED"},
+			"expiration": &types.AttributeValueMemberN{Value: "4.1e9"},
+		},
+	}}
+	store := &g8.DynamoDBIdempotencyStore{Client: client, TableName: "idempotency"}
+
+	_, err := store.Lock(context.Background(), g8.IdempotencyRecord{
+		Key:       "key-1",
+		Status:    g8.IdempotencyInProgress,
+		ExpiresAt: time.Now().Add(time.Minute),
+	})
+
+	assert.ErrorContains(t, err, "invalid idempotency record expiration")
+}
+
+func testIdempotencyStore(t *testing.T, store g8.IdempotencyStore) {
+	ctx := context.Background()
+	lock := g8.IdempotencyRecord{
+		Key:       "key-1",
+		Status:    g8.IdempotencyInProgress,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	existing, err := store.Lock(ctx, lock)
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+
+	existing, err = store.Lock(ctx, lock)
+	require.NoError(t, err)
+	require.NotNil(t, existing)
+	assert.Equal(t, g8.IdempotencyInProgress, existing.Status)
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	err = store.Put(ctx, g8.IdempotencyRecord{
+		Key:       "key-1",
+		Status:    g8.IdempotencyCompleted,
+		Result:    []byte(`{"ok":true}`),
+		ExpiresAt: expiresAt,
+	})
+	require.NoError(t, err)
+
+	existing, err = store.Lock(ctx, lock)
+	require.NoError(t, err)
+	require.NotNil(t, existing)
+	assert.Equal(t, "key-1", existing.Key)
+	assert.Equal(t, g8.IdempotencyCompleted, existing.Status)
+	assert.Equal(t, []byte(`{"ok":true}`), existing.Result)
+	assert.True(t, expiresAt.Equal(existing.ExpiresAt))
+
+	require.NoError(t, store.Delete(ctx, "key-1"))
+	existing, err = store.Lock(ctx, lock)
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+
+	// expired records are replaced
+	_, err = store.Lock(ctx, g8.IdempotencyRecord{
+		Key:       "key-2",
+		Status:    g8.IdempotencyInProgress,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	lock.Key = "key-2"
+	existing, err = store.Lock(ctx, lock)
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+}
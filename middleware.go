@@ -0,0 +1,38 @@
+package g8
+
+// Middleware wrap handler funcs to add cross-cutting behaviour such as auth checks, timing or recovery. They are
+// registered on the HandlerConfig for the matching handler type and called in the order they are registered, the
+// first middleware being the outermost. Middleware run after the context has been set up so the logger, New Relic
+// transaction and correlation ID are available.
+
+type APIGatewayProxyMiddleware func(next APIGatewayProxyHandlerFunc) APIGatewayProxyHandlerFunc
+
+type APIGatewayV2HTTPMiddleware func(next APIGatewayV2HTTPHandlerFunc) APIGatewayV2HTTPHandlerFunc
+
+type ALBMiddleware func(next ALBHandlerFunc) ALBHandlerFunc
+
+type FunctionURLMiddleware func(next FunctionURLHandlerFunc) FunctionURLHandlerFunc
+
+type FunctionURLStreamingMiddleware func(next FunctionURLStreamingHandlerFunc) FunctionURLStreamingHandlerFunc
+
+type WebSocketMiddleware func(next WebSocketHandlerFunc) WebSocketHandlerFunc
+
+type APIGatewayCustomAuthorizerMiddleware func(next APIGatewayCustomAuthorizerHandlerFunc) APIGatewayCustomAuthorizerHandlerFunc
+
+type SQSMiddleware func(next SQSHandlerFunc) SQSHandlerFunc
+
+type S3Middleware func(next S3HandlerFunc) S3HandlerFunc
+
+type DynamoDbMiddleware func(next DynamoHandlerFunc) DynamoHandlerFunc
+
+type StepMiddleware func(next StepHandlerFunc) StepHandlerFunc
+
+type CloudWatchMiddleware func(next CloudWatchHandlerFunc) CloudWatchHandlerFunc
+
+// chainMiddleware wraps the handler with the middleware so that the first middleware is called first
+func chainMiddleware[H any, M ~func(H) H](h H, middleware []M) H {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
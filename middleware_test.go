@@ -0,0 +1,268 @@
+package g8_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	adapter "github.com/jfallis/lambda-proxy-http-adapter"
+	"github.com/rs/zerolog"
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JSainsburyPLC/g8"
+)
+
+func TestAPIGatewayProxyHandler_Middleware(t *testing.T) {
+	var calls []string
+	mw := func(name string) g8.APIGatewayProxyMiddleware {
+		return func(next g8.APIGatewayProxyHandlerFunc) g8.APIGatewayProxyHandlerFunc {
+			return func(c *g8.APIGatewayProxyContext) error {
+				assert.NotEmpty(t, c.CorrelationID)
+				calls = append(calls, name+" before")
+				err := next(c)
+				calls = append(calls, name+" after")
+				return err
+			}
+		}
+	}
+
+	h := func(c *g8.APIGatewayProxyContext) error {
+		calls = append(calls, "handler")
+		return c.JSON(http.StatusOK, nil)
+	}
+
+	lh := g8.APIGatewayProxyHandler(h, g8.HandlerConfig{
+		APIGatewayProxyMiddleware: []g8.APIGatewayProxyMiddleware{mw("first"), mw("second")},
+	})
+
+	apitest.New().
+		Handler(adapter.GetHTTPHandlerWithContext(lh, "/", nil, nil)).
+		Get("/").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	assert.Equal(t, []string{"first before", "second before", "handler", "second after", "first after"}, calls)
+}
+
+func TestAPIGatewayProxyHandler_MiddlewareShortCircuit(t *testing.T) {
+	auth := func(next g8.APIGatewayProxyHandlerFunc) g8.APIGatewayProxyHandlerFunc {
+		return func(c *g8.APIGatewayProxyContext) error {
+			if c.GetHeader("Authorization") == "" {
+				return g8.Err{Status: http.StatusUnauthorized, Code: "UNAUTHORIZED", Detail: "Unauthorized"}
+			}
+			return next(c)
+		}
+	}
+
+	h := func(c *g8.APIGatewayProxyContext) error {
+		t.Fatal("handler should not be called")
+		return nil
+	}
+
+	lh := g8.APIGatewayProxyHandler(h, g8.HandlerConfig{
+		APIGatewayProxyMiddleware: []g8.APIGatewayProxyMiddleware{auth},
+	})
+
+	apitest.New().
+		Handler(adapter.GetHTTPHandlerWithContext(lh, "/", nil, nil)).
+		Get("/").
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Body(`{"code":"UNAUTHORIZED","detail":"Unauthorized"}`).
+		End()
+}
+
+func TestRouter_Use(t *testing.T) {
+	var calls []string
+	r := g8.NewRouter()
+	r.Use(func(next g8.APIGatewayProxyHandlerFunc) g8.APIGatewayProxyHandlerFunc {
+		return func(c *g8.APIGatewayProxyContext) error {
+			calls = append(calls, "router "+c.Request.PathParameters["id"])
+			return next(c)
+		}
+	})
+	r.Get("/users/{id}", func(c *g8.APIGatewayProxyContext) error {
+		calls = append(calls, "handler")
+		return c.JSON(http.StatusOK, nil)
+	})
+
+	lh := g8.APIGatewayProxyHandler(r.Dispatch, g8.HandlerConfig{
+		APIGatewayProxyMiddleware: []g8.APIGatewayProxyMiddleware{
+			func(next g8.APIGatewayProxyHandlerFunc) g8.APIGatewayProxyHandlerFunc {
+				return func(c *g8.APIGatewayProxyContext) error {
+					calls = append(calls, "handler config")
+					return next(c)
+				}
+			},
+		},
+	})
+
+	apitest.New().
+		Handler(adapter.GetHTTPHandlerWithContext(lh, "/{proxy+}", nil, nil)).
+		Get("/users/123").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	assert.Equal(t, []string{"handler config", "router 123", "handler"}, calls)
+}
+
+func TestAPIGatewayCustomAuthorizerHandler_Middleware(t *testing.T) {
+	var calls []string
+	h := g8.APIGatewayCustomAuthorizerHandler(func(c *g8.APIGatewayCustomAuthorizerContext) error {
+		calls = append(calls, "handler")
+		c.SetPrincipalID("principal")
+		c.AllowAllMethods()
+		return nil
+	}, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+		APIGatewayCustomAuthorizerMiddleware: []g8.APIGatewayCustomAuthorizerMiddleware{
+			func(next g8.APIGatewayCustomAuthorizerHandlerFunc) g8.APIGatewayCustomAuthorizerHandlerFunc {
+				return func(c *g8.APIGatewayCustomAuthorizerContext) error {
+					calls = append(calls, "middleware")
+					return next(c)
+				}
+			},
+		},
+	})
+
+	res, err := h(context.Background(), events.APIGatewayCustomAuthorizerRequestTypeRequest{
+		MethodArn: "arn:aws:execute-api:eu-west-1:123456789012:api-id/stage/GET/resource",
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "principal", res.PrincipalID)
+	assert.Equal(t, []string{"middleware", "handler"}, calls)
+}
+
+func TestSQSHandler_Middleware(t *testing.T) {
+	var calls []string
+	h := g8.SQSHandler(func(c *g8.SQSContext) error {
+		calls = append(calls, "handler "+c.Message.MessageId)
+		return nil
+	}, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+		SQSMiddleware: []g8.SQSMiddleware{
+			func(next g8.SQSHandlerFunc) g8.SQSHandlerFunc {
+				return func(c *g8.SQSContext) error {
+					calls = append(calls, "first "+c.Message.MessageId)
+					return next(c)
+				}
+			},
+			func(next g8.SQSHandlerFunc) g8.SQSHandlerFunc {
+				return func(c *g8.SQSContext) error {
+					calls = append(calls, "second "+c.Message.MessageId)
+					return next(c)
+				}
+			},
+		},
+	})
+
+	err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "1", Body: `{}`},
+		{MessageId: "2", Body: `{}`},
+	}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"first 1", "second 1", "handler 1", "first 2", "second 2", "handler 2"}, calls)
+}
+
+func TestS3Handler_Middleware(t *testing.T) {
+	var calls []string
+	h := g8.S3Handler(func(c *g8.S3Context) error {
+		calls = append(calls, "handler")
+		return nil
+	}, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+		S3Middleware: []g8.S3Middleware{
+			func(next g8.S3HandlerFunc) g8.S3HandlerFunc {
+				return func(c *g8.S3Context) error {
+					calls = append(calls, "middleware "+c.EventRecord.S3.Object.Key)
+					return next(c)
+				}
+			},
+		},
+	})
+
+	err := h(context.Background(), events.S3Event{Records: []events.S3EventRecord{
+		{S3: events.S3Entity{Object: events.S3Object{Key: "12345"}}},
+	}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"middleware 12345", "handler"}, calls)
+}
+
+func TestDynamoDbHandler_Middleware(t *testing.T) {
+	var calls []string
+	h := g8.DynamoDbHandler(func(c *g8.DynamoDbContext) error {
+		calls = append(calls, "handler")
+		return nil
+	}, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+		DynamoDbMiddleware: []g8.DynamoDbMiddleware{
+			func(next g8.DynamoHandlerFunc) g8.DynamoHandlerFunc {
+				return func(c *g8.DynamoDbContext) error {
+					calls = append(calls, "middleware")
+					return assert.AnError
+				}
+			},
+		},
+	})
+
+	err := h(context.Background(), events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{{}}})
+
+	assert.Equal(t, assert.AnError, err)
+	assert.Equal(t, []string{"middleware"}, calls)
+}
+
+func TestStepHandler_Middleware(t *testing.T) {
+	h := g8.StepHandler(func(c *g8.StepContext) (g8.StepEvent, error) {
+		return "handler", nil
+	}, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+		StepMiddleware: []g8.StepMiddleware{
+			func(next g8.StepHandlerFunc) g8.StepHandlerFunc {
+				return func(c *g8.StepContext) (g8.StepEvent, error) {
+					res, err := next(c)
+					return res.(string) + " middleware", err
+				}
+			},
+		},
+	})
+
+	result, err := h(context.Background(), nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "handler middleware", result)
+}
+
+func TestCloudWatchHandler_Middleware(t *testing.T) {
+	h := g8.CloudWatchHandler(func(c *g8.CloudWatchContext) (g8.LambdaResult, error) {
+		return "handler", nil
+	}, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+		CloudWatchMiddleware: []g8.CloudWatchMiddleware{
+			func(next g8.CloudWatchHandlerFunc) g8.CloudWatchHandlerFunc {
+				return func(c *g8.CloudWatchContext) (g8.LambdaResult, error) {
+					res, err := next(c)
+					return res.(string) + " first", err
+				}
+			},
+			func(next g8.CloudWatchHandlerFunc) g8.CloudWatchHandlerFunc {
+				return func(c *g8.CloudWatchContext) (g8.LambdaResult, error) {
+					res, err := next(c)
+					return res.(string) + " second", err
+				}
+			},
+		},
+	})
+
+	result, err := h(context.Background(), events.CloudWatchEvent{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "handler second first", result)
+}
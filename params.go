@@ -0,0 +1,257 @@
+package g8
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/rotisserie/eris"
+)
+
+var ErrInvalidParams = Err{
+	Status: http.StatusBadRequest,
+	Code:   "INVALID_REQUEST_PARAMS",
+	Detail: "Invalid request parameters",
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// paramSources looks up the raw values of request parameters by tag name
+type paramSources map[string]func(name string) []string
+
+// BindParams populates the struct pointed to by v from the request path parameters, query string and headers,
+// using `path`, `query` and `header` struct tags, e.g.
+//
+//	type params struct {
+//		ID       string    `path:"id"`
+//		Page     int       `query:"page" default:"1"`
+//		Statuses []string  `query:"status"`
+//		Since    time.Time `query:"since"`
+//		Tenant   string    `header:"X-Tenant-Id" validate:"required"`
+//	}
+//
+// Values are converted to the field type, supporting strings, bools, numbers, time.Time (RFC 3339),
+// time.Duration, encoding.TextUnmarshaler and pointers to them. Slices are populated from multi-value query
+// params and headers, and the default tag is used when a param isn't present. Conversion errors are returned
+// as an ErrInvalidParams listing every invalid param, and the bound value is then validated in the same way
+// as Bind.
+func (c *APIGatewayProxyContext) BindParams(v interface{}) error {
+	headers := http.Header{}
+	for k, v := range c.Request.Headers {
+		headers.Set(k, v)
+	}
+	for k, vs := range c.Request.MultiValueHeaders {
+		headers.Del(k)
+		for _, v := range vs {
+			headers.Add(k, v)
+		}
+	}
+
+	return bindParams(v, paramSources{
+		"path": func(name string) []string {
+			if p, ok := c.Request.PathParameters[name]; ok {
+				return []string{p}
+			}
+			return nil
+		},
+		"query": func(name string) []string {
+			if q, ok := c.Request.MultiValueQueryStringParameters[name]; ok {
+				return q
+			}
+			if q, ok := c.Request.QueryStringParameters[name]; ok {
+				return []string{q}
+			}
+			return nil
+		},
+		"header": func(name string) []string {
+			return headers.Values(name)
+		},
+	}, c.validator)
+}
+
+func bindParams(v interface{}, sources paramSources, validator Validator) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return eris.Errorf("params must be bound to a struct pointer, got %T", v)
+	}
+
+	var fieldErrs []FieldError
+	bindParamFields(rv.Elem(), sources, &fieldErrs)
+	if len(fieldErrs) > 0 {
+		return ErrInvalidParams.WithFields(fieldErrs...)
+	}
+
+	return validate(validator, v)
+}
+
+func bindParamFields(v reflect.Value, sources paramSources, fieldErrs *[]FieldError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			bindParamFields(v.Field(i), sources, fieldErrs)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		name, values, ok := lookupParam(f, sources)
+		if !ok {
+			continue
+		}
+		// empty values, e.g. ?page=, are treated as absent unless the field is a string
+		if !isStringParam(f.Type) {
+			values = nonEmptyValues(values)
+		}
+		if len(values) == 0 {
+			def, hasDefault := f.Tag.Lookup("default")
+			if !hasDefault {
+				continue
+			}
+			values = []string{def}
+		}
+
+		if err := setParamValue(v.Field(i), values); err != nil {
+			*fieldErrs = append(*fieldErrs, FieldError{
+				Field:   name,
+				Code:    "INVALID_TYPE",
+				Message: fmt.Sprintf("must be %s", paramTypeName(f.Type)),
+			})
+		}
+	}
+}
+
+// isStringParam reports whether the param is a string, or a slice of or pointer to strings
+func isStringParam(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.String
+}
+
+func nonEmptyValues(values []string) []string {
+	var nonEmpty []string
+	for _, value := range values {
+		if value != "" {
+			nonEmpty = append(nonEmpty, value)
+		}
+	}
+	return nonEmpty
+}
+
+func lookupParam(f reflect.StructField, sources paramSources) (string, []string, bool) {
+	for _, source := range []string{"path", "query", "header"} {
+		if name, ok := f.Tag.Lookup(source); ok && name != "" {
+			return name, sources[source](name), true
+		}
+	}
+	return "", nil, false
+}
+
+func setParamValue(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Slice && !v.Type().Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setParamValue(slice.Index(i), []string{value}); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		ptr := reflect.New(v.Type().Elem())
+		if err := setParamValue(ptr.Elem(), values); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+
+	value := values[0]
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) && v.Type() != timeType {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch {
+	case v.Type() == timeType:
+		tm, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(tm))
+		return nil
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return eris.Errorf("unsupported param type %s", v.Type())
+	}
+	return nil
+}
+
+// paramTypeName describes the expected type of a param in an error message
+func paramTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return "an RFC 3339 time"
+	case t == durationType:
+		return "a duration"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a positive integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	}
+	return "a valid " + t.String()
+}
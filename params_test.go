@@ -0,0 +1,195 @@
+package g8_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	adapter "github.com/jfallis/lambda-proxy-http-adapter"
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JSainsburyPLC/g8"
+)
+
+type pagination struct {
+	Page    int `query:"page" default:"1"`
+	PerPage int `query:"per_page" default:"20" validate:"max=100"`
+}
+
+type listParams struct {
+	pagination
+	StoreID  string         `path:"storeId"`
+	Statuses []string       `query:"status"`
+	Since    *time.Time     `query:"since"`
+	Timeout  time.Duration  `query:"timeout"`
+	Active   bool           `query:"active"`
+	Ratio    float64        `query:"ratio"`
+	Tenant   string         `header:"X-Tenant-Id" validate:"required"`
+	Versions []uint         `header:"X-Version"`
+	Ignored  string         `json:"ignored"`
+	Optional *int           `query:"optional"`
+	Tags     map[string]int `json:"-"`
+}
+
+func TestAPIGatewayProxyContext_BindParams(t *testing.T) {
+	since := time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC)
+	zero := 0
+
+	testCases := map[string]struct {
+		request        events.APIGatewayProxyRequest
+		validator      g8.Validator
+		expectedParams listParams
+		expectedErr    error
+	}{
+		"all sources": {
+			request: events.APIGatewayProxyRequest{
+				PathParameters: map[string]string{"storeId": "store-1"},
+				QueryStringParameters: map[string]string{
+					"page":     "3",
+					"status":   "open",
+					"since":    "2023-09-01T10:00:00Z",
+					"timeout":  "1m30s",
+					"active":   "true",
+					"ratio":    "0.5",
+					"optional": "0",
+				},
+				MultiValueQueryStringParameters: map[string][]string{
+					"status": {"open", "closed"},
+				},
+				Headers: map[string]string{"x-tenant-id": "tenant-1"},
+				MultiValueHeaders: map[string][]string{
+					"X-Version": {"1", "2"},
+				},
+			},
+			expectedParams: listParams{
+				pagination: pagination{Page: 3, PerPage: 20},
+				StoreID:    "store-1",
+				Statuses:   []string{"open", "closed"},
+				Since:      &since,
+				Timeout:    90 * time.Second,
+				Active:     true,
+				Ratio:      0.5,
+				Tenant:     "tenant-1",
+				Versions:   []uint{1, 2},
+				Optional:   &zero,
+			},
+		},
+		"defaults": {
+			request: events.APIGatewayProxyRequest{},
+			expectedParams: listParams{
+				pagination: pagination{Page: 1, PerPage: 20},
+			},
+		},
+		"empty values": {
+			request: events.APIGatewayProxyRequest{
+				QueryStringParameters: map[string]string{
+					"page":     "",
+					"status":   "",
+					"active":   "",
+					"optional": "",
+				},
+				MultiValueHeaders: map[string][]string{
+					"X-Tenant-Id": {""},
+					"X-Version":   {"1", ""},
+				},
+			},
+			expectedParams: listParams{
+				pagination: pagination{Page: 1, PerPage: 20},
+				Statuses:   []string{""},
+				Versions:   []uint{1},
+			},
+		},
+		"conversion errors": {
+			request: events.APIGatewayProxyRequest{
+				QueryStringParameters: map[string]string{
+					"page":   "one",
+					"since":  "yesterday",
+					"active": "maybe",
+				},
+				MultiValueHeaders: map[string][]string{
+					"X-Version": {"1", "-2"},
+				},
+			},
+			expectedParams: listParams{
+				pagination: pagination{PerPage: 20},
+			},
+			expectedErr: g8.Err{
+				Status: http.StatusBadRequest,
+				Code:   "INVALID_REQUEST_PARAMS",
+				Detail: "Invalid request parameters",
+				Details: &g8.ErrDetails{Fields: []g8.FieldError{
+					{Field: "page", Code: "INVALID_TYPE", Message: "must be an integer"},
+					{Field: "since", Code: "INVALID_TYPE", Message: "must be an RFC 3339 time"},
+					{Field: "active", Code: "INVALID_TYPE", Message: "must be a boolean"},
+					{Field: "X-Version", Code: "INVALID_TYPE", Message: "must be a positive integer"},
+				}},
+			},
+		},
+		"validation": {
+			request: events.APIGatewayProxyRequest{
+				QueryStringParameters: map[string]string{"per_page": "500"},
+			},
+			validator: g8.TagValidator{},
+			expectedParams: listParams{
+				pagination: pagination{Page: 1, PerPage: 500},
+			},
+			expectedErr: g8.ValidationErrors{
+				{Field: "per_page", Code: "TOO_LARGE", Message: "must be at most 100"},
+				{Field: "X-Tenant-Id", Code: "REQUIRED", Message: "is required"},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var params listParams
+			var err error
+			lh := g8.APIGatewayProxyHandler(func(c *g8.APIGatewayProxyContext) error {
+				err = c.BindParams(&params)
+				return nil
+			}, g8.HandlerConfig{Validator: tc.validator})
+
+			_, _ = lh(context.Background(), tc.request)
+
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedParams, params)
+		})
+	}
+}
+
+func TestAPIGatewayProxyContext_BindParamsNotStructPointer(t *testing.T) {
+	c := &g8.APIGatewayProxyContext{}
+	var s string
+
+	err := c.BindParams(&s)
+
+	assert.EqualError(t, err, "params must be bound to a struct pointer, got *string")
+}
+
+func TestAPIGatewayProxyHandler_BindParamsErrorResponse(t *testing.T) {
+	h := func(c *g8.APIGatewayProxyContext) error {
+		var p pagination
+		if err := c.BindParams(&p); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, p)
+	}
+
+	lh := g8.APIGatewayProxyHandler(h, g8.HandlerConfig{})
+
+	apitest.New().
+		Handler(adapter.GetHTTPHandlerWithContext(lh, "/", nil, nil)).
+		Get("/").
+		Query("page", "first").
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Body(`{
+			"code": "INVALID_REQUEST_PARAMS",
+			"detail": "Invalid request parameters",
+			"fields": [{"field": "page", "code": "INVALID_TYPE", "message": "must be an integer"}]
+		}`).
+		End()
+}
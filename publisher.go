@@ -0,0 +1,233 @@
+package g8
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rotisserie/eris"
+)
+
+// maxBatchSize is the maximum number of entries in an SQS SendMessageBatch or SNS PublishBatch request
+const maxBatchSize = 10
+
+// maxBatchPayloadSize is the maximum total size in bytes of the entries in an SQS SendMessageBatch or SNS
+// PublishBatch request, 256 KiB
+const maxBatchPayloadSize = 256 * 1024
+
+// correlationIDAttribute is the message attribute the Publisher sets to the correlation ID
+const correlationIDAttribute = "correlation_id"
+
+// Message is published by the Publisher, with its Data wrapped in the SQSMessageEnvelope along with the Type,
+// SchemaVersion, CausationID and OccurredAt meta data. GroupID and DeduplicationID are only used by FIFO queues and
+// topics.
+type Message struct {
+	Data            interface{}
+	Type            string
+	SchemaVersion   int
+	CausationID     string
+	OccurredAt      time.Time
+	Attributes      map[string]string
+	GroupID         string
+	DeduplicationID string
+}
+
+// MessageEntry is a message sent by a MessageSender, with an ID unique within its batch
+type MessageEntry struct {
+	ID              string
+	Body            string
+	Attributes      map[string]string
+	GroupID         string
+	DeduplicationID string
+}
+
+// MessageSender sends batches of up to 10 messages totalling up to 256 KiB, e.g. to an SQS queue or SNS topic. The
+// errors of entries which failed are returned by entry ID, while an error is returned when the whole batch failed.
+type MessageSender interface {
+	SendMessages(ctx context.Context, entries []MessageEntry) (map[string]error, error)
+}
+
+// PublishFailure is a message which the Publisher failed to publish, with its index in the published messages
+type PublishFailure struct {
+	Index   int
+	Message Message
+	Err     error
+}
+
+// PublishError is returned by the Publisher when some of the messages failed to publish
+type PublishError struct {
+	Failures []PublishFailure
+}
+
+func (e *PublishError) Error() string {
+	reasons := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		reasons[i] = fmt.Sprintf("message %d: %s", f.Index, f.Err)
+	}
+	return fmt.Sprintf("failed to publish %d messages: %s", len(e.Failures), strings.Join(reasons, "; "))
+}
+
+// Publisher publishes messages in the SQSMessageEnvelope understood by SQSHandler, with the correlation ID of the
+// g8 context it's called from in both the envelope's meta and the correlation_id message attribute. The Producer,
+// e.g. the function name, is set in the meta of every message.
+type Publisher struct {
+	Sender   MessageSender
+	Producer string
+}
+
+func NewPublisher(sender MessageSender) *Publisher {
+	return &Publisher{Sender: sender}
+}
+
+// Publish sends the messages in batches of up to 10 messages totalling up to 256 KiB, using the correlation ID
+// carried by the context, e.g. the Context of a g8 context, or a new one when it doesn't carry one. A *PublishError is
+// returned when any of the messages failed to publish, so that they can be retried.
+func (p *Publisher) Publish(ctx context.Context, messages ...Message) error {
+	correlationID := CorrelationIDFromContext(ctx)
+	if correlationID == "" {
+		correlationID = uuid.New().String()
+	}
+
+	var failures []PublishFailure
+	var batch []MessageEntry
+	batchSize := 0
+	for i, m := range messages {
+		entry, err := p.newMessageEntry(strconv.Itoa(i), m, correlationID)
+		if err != nil {
+			failures = append(failures, PublishFailure{Index: i, Message: m, Err: err})
+			continue
+		}
+
+		// a message larger than the limit is sent on its own, failing with the sender's error
+		size := entry.size()
+		if len(batch) == maxBatchSize || (len(batch) > 0 && batchSize+size > maxBatchPayloadSize) {
+			failures = append(failures, p.publishBatch(ctx, batch, messages)...)
+			batch, batchSize = nil, 0
+		}
+		batch = append(batch, entry)
+		batchSize += size
+	}
+	if len(batch) > 0 {
+		failures = append(failures, p.publishBatch(ctx, batch, messages)...)
+	}
+
+	if len(failures) > 0 {
+		sort.SliceStable(failures, func(i, j int) bool { return failures[i].Index < failures[j].Index })
+		return &PublishError{Failures: failures}
+	}
+	return nil
+}
+
+// publishBatch sends the entries, whose IDs are the indexes of their messages
+func (p *Publisher) publishBatch(ctx context.Context, entries []MessageEntry, messages []Message) []PublishFailure {
+	var failures []PublishFailure
+	entryErrs, err := p.Sender.SendMessages(ctx, entries)
+	for _, entry := range entries {
+		entryErr := entryErrs[entry.ID]
+		if err != nil {
+			entryErr = err
+		}
+		if entryErr != nil {
+			i, _ := strconv.Atoi(entry.ID)
+			failures = append(failures, PublishFailure{Index: i, Message: messages[i], Err: entryErr})
+		}
+	}
+	return failures
+}
+
+func (p *Publisher) newMessageEntry(id string, m Message, correlationID string) (MessageEntry, error) {
+	body, err := json.Marshal(SQSMessageEnvelope{
+		Data: m.Data,
+		Meta: &SQSMessageMeta{
+			CorrelationID: correlationID,
+			CausationID:   m.CausationID,
+			Type:          m.Type,
+			SchemaVersion: m.SchemaVersion,
+			Producer:      p.Producer,
+			OccurredAt:    m.OccurredAt,
+			PublishedAt:   time.Now().UTC(),
+		},
+	})
+	if err != nil {
+		return MessageEntry{}, eris.Wrap(err, "failed to marshal message")
+	}
+
+	attributes := make(map[string]string, len(m.Attributes)+1)
+	for k, v := range m.Attributes {
+		attributes[k] = v
+	}
+	attributes[correlationIDAttribute] = correlationID
+
+	return MessageEntry{
+		ID:              id,
+		Body:            string(body),
+		Attributes:      attributes,
+		GroupID:         m.GroupID,
+		DeduplicationID: m.DeduplicationID,
+	}, nil
+}
+
+// size is the entry's size counted towards the batch payload limit, its body along with the names, data types and
+// values of its attributes
+func (e MessageEntry) size() int {
+	size := len(e.Body)
+	for k, v := range e.Attributes {
+		size += len(k) + len("String") + len(v)
+	}
+	return size
+}
+
+// InMemoryMessageSender is a MessageSender which records the messages sent, e.g. for tests. Fail can be set to fail
+// the entries it returns an error for.
+type InMemoryMessageSender struct {
+	Fail func(entry MessageEntry) error
+
+	mu      sync.Mutex
+	batches [][]MessageEntry
+}
+
+func NewInMemoryMessageSender() *InMemoryMessageSender {
+	return &InMemoryMessageSender{}
+}
+
+func (s *InMemoryMessageSender) SendMessages(_ context.Context, entries []MessageEntry) (map[string]error, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failed := make(map[string]error)
+	var sent []MessageEntry
+	for _, entry := range entries {
+		if s.Fail != nil {
+			if err := s.Fail(entry); err != nil {
+				failed[entry.ID] = err
+				continue
+			}
+		}
+		sent = append(sent, entry)
+	}
+	s.batches = append(s.batches, sent)
+	return failed, nil
+}
+
+// Batches returns the entries sent successfully in each batch, in the order they were sent
+func (s *InMemoryMessageSender) Batches() [][]MessageEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]MessageEntry(nil), s.batches...)
+}
+
+// Messages returns the entries sent successfully
+func (s *InMemoryMessageSender) Messages() []MessageEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var messages []MessageEntry
+	for _, batch := range s.batches {
+		messages = append(messages, batch...)
+	}
+	return messages
+}
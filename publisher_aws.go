@@ -0,0 +1,105 @@
+package g8
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/rotisserie/eris"
+)
+
+// SQSSendMessageBatchAPI is the subset of the SQS client used by SQSSender, implemented by *sqs.Client
+type SQSSendMessageBatchAPI interface {
+	SendMessageBatch(
+		ctx context.Context,
+		in *sqs.SendMessageBatchInput,
+		optFns ...func(*sqs.Options),
+	) (*sqs.SendMessageBatchOutput, error)
+}
+
+// SQSSender is a MessageSender which sends messages to an SQS queue
+type SQSSender struct {
+	Client   SQSSendMessageBatchAPI
+	QueueURL string
+}
+
+func (s *SQSSender) SendMessages(ctx context.Context, entries []MessageEntry) (map[string]error, error) {
+	in := &sqs.SendMessageBatchInput{QueueUrl: aws.String(s.QueueURL)}
+	for _, e := range entries {
+		attributes := make(map[string]sqstypes.MessageAttributeValue, len(e.Attributes))
+		for k, v := range e.Attributes {
+			attributes[k] = sqstypes.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(v)}
+		}
+		in.Entries = append(in.Entries, sqstypes.SendMessageBatchRequestEntry{
+			Id:                     aws.String(e.ID),
+			MessageBody:            aws.String(e.Body),
+			MessageAttributes:      attributes,
+			MessageGroupId:         optionalString(e.GroupID),
+			MessageDeduplicationId: optionalString(e.DeduplicationID),
+		})
+	}
+
+	out, err := s.Client.SendMessageBatch(ctx, in)
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to send sqs message batch")
+	}
+	failed := make(map[string]error, len(out.Failed))
+	for _, f := range out.Failed {
+		failed[aws.ToString(f.Id)] = eris.Errorf("%s: %s", aws.ToString(f.Code), aws.ToString(f.Message))
+	}
+	return failed, nil
+}
+
+// SNSPublishBatchAPI is the subset of the SNS client used by SNSSender, implemented by *sns.Client
+type SNSPublishBatchAPI interface {
+	PublishBatch(
+		ctx context.Context,
+		in *sns.PublishBatchInput,
+		optFns ...func(*sns.Options),
+	) (*sns.PublishBatchOutput, error)
+}
+
+// SNSSender is a MessageSender which publishes messages to an SNS topic. Queues subscribed to the topic receive the
+// envelope as the SNS notification's message, which SQSHandler unwraps when SQSUnwrapBody is set, or as the body
+// itself when raw message delivery is enabled.
+type SNSSender struct {
+	Client   SNSPublishBatchAPI
+	TopicArn string
+}
+
+func (s *SNSSender) SendMessages(ctx context.Context, entries []MessageEntry) (map[string]error, error) {
+	in := &sns.PublishBatchInput{TopicArn: aws.String(s.TopicArn)}
+	for _, e := range entries {
+		attributes := make(map[string]snstypes.MessageAttributeValue, len(e.Attributes))
+		for k, v := range e.Attributes {
+			attributes[k] = snstypes.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(v)}
+		}
+		in.PublishBatchRequestEntries = append(in.PublishBatchRequestEntries, snstypes.PublishBatchRequestEntry{
+			Id:                     aws.String(e.ID),
+			Message:                aws.String(e.Body),
+			MessageAttributes:      attributes,
+			MessageGroupId:         optionalString(e.GroupID),
+			MessageDeduplicationId: optionalString(e.DeduplicationID),
+		})
+	}
+
+	out, err := s.Client.PublishBatch(ctx, in)
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to publish sns message batch")
+	}
+	failed := make(map[string]error, len(out.Failed))
+	for _, f := range out.Failed {
+		failed[aws.ToString(f.Id)] = eris.Errorf("%s: %s", aws.ToString(f.Code), aws.ToString(f.Message))
+	}
+	return failed, nil
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
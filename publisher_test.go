@@ -0,0 +1,251 @@
+package g8_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/JSainsburyPLC/g8"
+)
+
+func TestPublisher_PublishFromContext(t *testing.T) {
+	sender := g8.NewInMemoryMessageSender()
+	publisher := g8.NewPublisher(sender)
+
+	h := g8.APIGatewayProxyHandler(func(c *g8.APIGatewayProxyContext) error {
+		err := publisher.Publish(c.Context, g8.Message{
+			Data:       map[string]string{"order_id": "1"},
+			Attributes: map[string]string{"type": "OrderPlaced"},
+		})
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusAccepted, nil)
+	}, g8.HandlerConfig{Logger: zerolog.New(io.Discard)})
+
+	_, err := h(context.Background(), events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Correlation-Id": "abcdef"},
+	})
+	require.NoError(t, err)
+
+	messages := sender.Messages()
+	require.Len(t, messages, 1)
+	envelope := unmarshalEnvelope(t, messages[0].Body)
+	assert.Equal(t, map[string]interface{}{"order_id": "1"}, envelope.Data)
+	assert.Equal(t, "abcdef", envelope.Meta.CorrelationID)
+	assert.Equal(t, map[string]string{"type": "OrderPlaced", "correlation_id": "abcdef"}, messages[0].Attributes)
+
+	// the published message is understood by SQSHandler
+	var data map[string]string
+	var correlationID string
+	sqsHandler := g8.SQSHandler(func(c *g8.SQSContext) error {
+		correlationID = c.CorrelationID
+		return c.Bind(&data)
+	}, g8.HandlerConfig{Logger: zerolog.New(io.Discard)})
+	err = sqsHandler(context.Background(), events.SQSEvent{Records: []events.SQSMessage{{Body: messages[0].Body}}})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"order_id": "1"}, data)
+	assert.Equal(t, "abcdef", correlationID)
+}
+
+func TestPublisher_Batches(t *testing.T) {
+	sender := g8.NewInMemoryMessageSender()
+	ctx := g8.ContextWithCorrelationID(context.Background(), "abcdef")
+
+	messages := make([]g8.Message, 25)
+	for i := range messages {
+		messages[i] = g8.Message{Data: i}
+	}
+	err := g8.NewPublisher(sender).Publish(ctx, messages...)
+
+	require.NoError(t, err)
+	batches := sender.Batches()
+	require.Len(t, batches, 3)
+	assert.Len(t, batches[0], 10)
+	assert.Len(t, batches[1], 10)
+	assert.Len(t, batches[2], 5)
+	assert.Equal(t, "24", batches[2][4].ID)
+	envelope := unmarshalEnvelope(t, batches[2][4].Body)
+	assert.Equal(t, float64(24), envelope.Data)
+	assert.Equal(t, "abcdef", envelope.Meta.CorrelationID)
+}
+
+func TestPublisher_BatchesByPayloadSize(t *testing.T) {
+	sender := g8.NewInMemoryMessageSender()
+
+	large := strings.Repeat("a", 100*1024)
+	err := g8.NewPublisher(sender).Publish(context.Background(),
+		g8.Message{Data: large},
+		g8.Message{Data: large},
+		g8.Message{Data: large},
+		g8.Message{Data: "small"},
+	)
+
+	require.NoError(t, err)
+	batches := sender.Batches()
+	require.Len(t, batches, 2)
+	assert.Len(t, batches[0], 2)
+	assert.Len(t, batches[1], 2)
+	for _, batch := range batches {
+		size := 0
+		for _, entry := range batch {
+			size += len(entry.Body)
+			for k, v := range entry.Attributes {
+				size += len(k) + len("String") + len(v)
+			}
+		}
+		assert.LessOrEqual(t, size, 256*1024)
+	}
+	assert.Equal(t, "2", batches[1][0].ID)
+	assert.Equal(t, "3", batches[1][1].ID)
+}
+
+func TestPublisher_EntryFailures(t *testing.T) {
+	sender := g8.NewInMemoryMessageSender()
+	sender.Fail = func(entry g8.MessageEntry) error {
+		if entry.ID == "11" {
+			return errors.New("throttled")
+		}
+		return nil
+	}
+
+	messages := make([]g8.Message, 12)
+	for i := range messages {
+		messages[i] = g8.Message{Data: i}
+	}
+	messages[3] = g8.Message{Data: make(chan int)}
+	err := g8.NewPublisher(sender).Publish(context.Background(), messages...)
+
+	var pErr *g8.PublishError
+	require.ErrorAs(t, err, &pErr)
+	require.Len(t, pErr.Failures, 2)
+	assert.Equal(t, 3, pErr.Failures[0].Index)
+	assert.Contains(t, pErr.Failures[0].Err.Error(), "failed to marshal message")
+	assert.Equal(t, 11, pErr.Failures[1].Index)
+	assert.Equal(t, g8.Message{Data: 11}, pErr.Failures[1].Message)
+	assert.EqualError(t, pErr.Failures[1].Err, "throttled")
+	assert.Len(t, sender.Messages(), 10)
+}
+
+type sendMessagesFunc func(ctx context.Context, entries []g8.MessageEntry) (map[string]error, error)
+
+func (f sendMessagesFunc) SendMessages(ctx context.Context, entries []g8.MessageEntry) (map[string]error, error) {
+	return f(ctx, entries)
+}
+
+func TestPublisher_BatchFailure(t *testing.T) {
+	calls := 0
+	sender := sendMessagesFunc(func(ctx context.Context, entries []g8.MessageEntry) (map[string]error, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("unavailable")
+		}
+		return nil, nil
+	})
+
+	messages := make([]g8.Message, 12)
+	err := g8.NewPublisher(sender).Publish(context.Background(), messages...)
+
+	var pErr *g8.PublishError
+	require.ErrorAs(t, err, &pErr)
+	assert.Len(t, pErr.Failures, 10)
+	assert.Equal(t, 2, calls)
+}
+
+type fakeSQSClient struct {
+	input *sqs.SendMessageBatchInput
+}
+
+func (f *fakeSQSClient) SendMessageBatch(
+	_ context.Context,
+	in *sqs.SendMessageBatchInput,
+	_ ...func(*sqs.Options),
+) (*sqs.SendMessageBatchOutput, error) {
+	f.input = in
+	return &sqs.SendMessageBatchOutput{
+		Failed: []sqstypes.BatchResultErrorEntry{
+			{Id: aws.String("1"), Code: aws.String("InvalidParameterValue"), Message: aws.String("too big")},
+		},
+	}, nil
+}
+
+func TestSQSSender(t *testing.T) {
+	client := &fakeSQSClient{}
+	sender := &g8.SQSSender{Client: client, QueueURL: "https://sqs.eu-west-1.amazonaws.com/123456789012/orders.fifo"}
+
+	err := g8.NewPublisher(sender).Publish(
+		g8.ContextWithCorrelationID(context.Background(), "abcdef"),
+		g8.Message{Data: "one", GroupID: "order-1"},
+		g8.Message{Data: "two"},
+	)
+
+	var pErr *g8.PublishError
+	require.ErrorAs(t, err, &pErr)
+	require.Len(t, pErr.Failures, 1)
+	assert.Equal(t, 1, pErr.Failures[0].Index)
+	assert.EqualError(t, pErr.Failures[0].Err, "InvalidParameterValue: too big")
+
+	assert.Equal(t, "https://sqs.eu-west-1.amazonaws.com/123456789012/orders.fifo", aws.ToString(client.input.QueueUrl))
+	require.Len(t, client.input.Entries, 2)
+	entry := client.input.Entries[0]
+	assert.Equal(t, "0", aws.ToString(entry.Id))
+	assert.Equal(t, "order-1", aws.ToString(entry.MessageGroupId))
+	assert.Nil(t, entry.MessageDeduplicationId)
+	assert.Equal(t, "abcdef", aws.ToString(entry.MessageAttributes["correlation_id"].StringValue))
+
+	envelope := unmarshalEnvelope(t, aws.ToString(entry.MessageBody))
+	assert.Equal(t, "one", envelope.Data)
+	assert.Equal(t, "abcdef", envelope.Meta.CorrelationID)
+}
+
+func TestPublisher_Meta(t *testing.T) {
+	sender := g8.NewInMemoryMessageSender()
+	publisher := &g8.Publisher{Sender: sender, Producer: "orders-api"}
+	occurredAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	err := publisher.Publish(context.Background(), g8.Message{
+		Data:          "one",
+		Type:          "OrderPlaced",
+		SchemaVersion: 2,
+		CausationID:   "cause-1",
+		OccurredAt:    occurredAt,
+	}, g8.Message{Data: "two"})
+
+	require.NoError(t, err)
+	messages := sender.Messages()
+	meta := unmarshalEnvelope(t, messages[0].Body).Meta
+	assert.Equal(t, "OrderPlaced", meta.Type)
+	assert.Equal(t, 2, meta.SchemaVersion)
+	assert.Equal(t, "cause-1", meta.CausationID)
+	assert.Equal(t, "orders-api", meta.Producer)
+	assert.True(t, occurredAt.Equal(meta.OccurredAt))
+	assert.WithinDuration(t, time.Now(), meta.PublishedAt, time.Minute)
+
+	var raw struct {
+		Meta map[string]interface{} `json:"meta"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(messages[1].Body), &raw))
+	assert.NotContains(t, raw.Meta, "occurred_at")
+	assert.NotContains(t, raw.Meta, "type")
+}
+
+func unmarshalEnvelope(t *testing.T, body string) g8.SQSMessageEnvelope {
+	var envelope g8.SQSMessageEnvelope
+	require.NoError(t, json.Unmarshal([]byte(body), &envelope))
+	require.NotNil(t, envelope.Meta)
+	return envelope
+}
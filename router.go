@@ -0,0 +1,219 @@
+package g8
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// MethodAny matches every HTTP method, mirroring the API Gateway ANY method
+const MethodAny = "ANY"
+
+// Router dispatches API Gateway proxy requests to handlers registered by HTTP method and path pattern,
+// allowing a single lambda to serve many routes behind a greedy {proxy+} resource.
+//
+// Path patterns are made up of literal segments, {param} segments which match a single path segment and
+// a trailing {param+} segment which greedily matches the rest of the path, e.g. "/users/{id}/files/{path+}".
+// When more than one pattern matches a path the most specific is used, literals taking precedence over
+// params and params over greedy params. Of the routes with the most specific pattern, one registered for the
+// request method takes precedence over one registered for MethodAny, as in API Gateway.
+type Router struct {
+	routes     []route
+	middleware []APIGatewayProxyMiddleware
+}
+
+type route struct {
+	method   string
+	segments []routeSegment
+	handler  APIGatewayProxyHandlerFunc
+}
+
+type segmentKind int
+
+const (
+	segmentGreedy segmentKind = iota
+	segmentParam
+	segmentLiteral
+)
+
+type routeSegment struct {
+	kind  segmentKind
+	value string
+}
+
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Use registers middleware which wrap every route's handler. They are called after the route has been matched,
+// so the request path parameters are available.
+func (r *Router) Use(middleware ...APIGatewayProxyMiddleware) {
+	r.middleware = append(r.middleware, middleware...)
+}
+
+// Handle registers the handler for the given method and path pattern
+func (r *Router) Handle(method, pattern string, h APIGatewayProxyHandlerFunc) {
+	r.routes = append(r.routes, route{
+		method:   strings.ToUpper(method),
+		segments: parsePathPattern(pattern),
+		handler:  h,
+	})
+}
+
+func (r *Router) Get(pattern string, h APIGatewayProxyHandlerFunc) {
+	r.Handle(http.MethodGet, pattern, h)
+}
+
+func (r *Router) Post(pattern string, h APIGatewayProxyHandlerFunc) {
+	r.Handle(http.MethodPost, pattern, h)
+}
+
+func (r *Router) Put(pattern string, h APIGatewayProxyHandlerFunc) {
+	r.Handle(http.MethodPut, pattern, h)
+}
+
+func (r *Router) Patch(pattern string, h APIGatewayProxyHandlerFunc) {
+	r.Handle(http.MethodPatch, pattern, h)
+}
+
+func (r *Router) Delete(pattern string, h APIGatewayProxyHandlerFunc) {
+	r.Handle(http.MethodDelete, pattern, h)
+}
+
+// Dispatch is an APIGatewayProxyHandlerFunc which calls the handler registered for the request method and path,
+// populating the request path parameters from the matched pattern. It responds with ErrNotFound when no pattern
+// matches the path, and ErrMethodNotAllowed with an Allow header when the path matches but the method does not.
+func (r *Router) Dispatch(c *APIGatewayProxyContext) error {
+	var best *route
+	var bestParams map[string]string
+	var bestRank []segmentKind
+	allowed := make(map[string]bool)
+
+	for i := range r.routes {
+		rt := &r.routes[i]
+		params, ok := matchPathPattern(rt.segments, c.Request.Path)
+		if !ok {
+			continue
+		}
+		if rt.method != MethodAny && rt.method != strings.ToUpper(c.Request.HTTPMethod) {
+			allowed[rt.method] = true
+			continue
+		}
+		rank := rt.rank()
+		cmp := 0
+		if best != nil {
+			cmp = compareRank(rank, bestRank)
+		}
+		if best == nil || cmp > 0 || cmp == 0 && best.method == MethodAny && rt.method != MethodAny {
+			best, bestParams, bestRank = rt, params, rank
+		}
+	}
+
+	if best == nil {
+		if len(allowed) == 0 {
+			return ErrNotFound
+		}
+		methods := make([]string, 0, len(allowed))
+		for m := range allowed {
+			methods = append(methods, m)
+		}
+		sort.Strings(methods)
+		if c.Response.Headers == nil {
+			c.Response.Headers = make(map[string]string)
+		}
+		c.Response.Headers["Allow"] = strings.Join(methods, ", ")
+		return ErrMethodNotAllowed
+	}
+
+	pathParams := make(map[string]string, len(c.Request.PathParameters)+len(bestParams))
+	for k, v := range c.Request.PathParameters {
+		pathParams[k] = v
+	}
+	for k, v := range bestParams {
+		pathParams[k] = v
+	}
+	c.Request.PathParameters = pathParams
+
+	return chainMiddleware(best.handler, r.middleware)(c)
+}
+
+func (rt *route) rank() []segmentKind {
+	rank := make([]segmentKind, len(rt.segments))
+	for i, s := range rt.segments {
+		rank[i] = s.kind
+	}
+	return rank
+}
+
+// compareRank compares the specificity of two matched patterns segment by segment
+func compareRank(a, b []segmentKind) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return int(a[i]) - int(b[i])
+		}
+	}
+	return len(a) - len(b)
+}
+
+func parsePathPattern(pattern string) []routeSegment {
+	parts := splitPath(pattern)
+	segments := make([]routeSegment, 0, len(parts))
+	for _, p := range parts {
+		switch {
+		case strings.HasPrefix(p, "{") && strings.HasSuffix(p, "+}"):
+			segments = append(segments, routeSegment{kind: segmentGreedy, value: p[1 : len(p)-2]})
+		case strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}"):
+			segments = append(segments, routeSegment{kind: segmentParam, value: p[1 : len(p)-1]})
+		default:
+			segments = append(segments, routeSegment{kind: segmentLiteral, value: p})
+		}
+	}
+	return segments
+}
+
+// matchPathPattern matches the path against the parsed pattern, returning the path parameter values
+func matchPathPattern(segments []routeSegment, path string) (map[string]string, bool) {
+	parts := splitPath(path)
+	params := make(map[string]string)
+	for i, s := range segments {
+		if s.kind == segmentGreedy {
+			if i >= len(parts) {
+				return nil, false
+			}
+			params[s.value] = strings.Join(parts[i:], "/")
+			return params, true
+		}
+		if i >= len(parts) {
+			return nil, false
+		}
+		switch s.kind {
+		case segmentParam:
+			params[s.value] = parts[i]
+		case segmentLiteral:
+			if s.value != parts[i] {
+				return nil, false
+			}
+		}
+	}
+	if len(parts) != len(segments) {
+		return nil, false
+	}
+	return params, true
+}
+
+func hasGreedySegment(pattern string) bool {
+	for _, s := range parsePathPattern(pattern) {
+		if s.kind == segmentGreedy {
+			return true
+		}
+	}
+	return false
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
@@ -0,0 +1,193 @@
+package g8_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	adapter "github.com/jfallis/lambda-proxy-http-adapter"
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JSainsburyPLC/g8"
+)
+
+func newTestRouter() *g8.Router {
+	r := g8.NewRouter()
+	r.Get("/users", func(c *g8.APIGatewayProxyContext) error {
+		return c.JSON(http.StatusOK, map[string]string{"route": "list"})
+	})
+	r.Get("/users/{id}", func(c *g8.APIGatewayProxyContext) error {
+		return c.JSON(http.StatusOK, map[string]string{"route": "get", "id": c.Request.PathParameters["id"]})
+	})
+	r.Get("/users/me", func(c *g8.APIGatewayProxyContext) error {
+		return c.JSON(http.StatusOK, map[string]string{"route": "me"})
+	})
+	r.Delete("/users/{id}", func(c *g8.APIGatewayProxyContext) error {
+		return c.JSON(http.StatusNoContent, nil)
+	})
+	r.Handle(g8.MethodAny, "/files/{path+}", func(c *g8.APIGatewayProxyContext) error {
+		return c.JSON(http.StatusOK, map[string]string{"route": "files", "path": c.Request.PathParameters["path"]})
+	})
+	return r
+}
+
+func TestRouter_Dispatch(t *testing.T) {
+	testCases := map[string]struct {
+		method       string
+		path         string
+		expectedCode int
+		expectedBody string
+	}{
+		"literal route": {
+			method:       http.MethodGet,
+			path:         "/users",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"route":"list"}`,
+		},
+		"trailing slash": {
+			method:       http.MethodGet,
+			path:         "/users/",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"route":"list"}`,
+		},
+		"path param": {
+			method:       http.MethodGet,
+			path:         "/users/123",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"route":"get","id":"123"}`,
+		},
+		"literal takes precedence over param": {
+			method:       http.MethodGet,
+			path:         "/users/me",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"route":"me"}`,
+		},
+		"greedy param": {
+			method:       http.MethodPut,
+			path:         "/files/a/b/c.txt",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"route":"files","path":"a/b/c.txt"}`,
+		},
+		"greedy param requires a segment": {
+			method:       http.MethodGet,
+			path:         "/files",
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"code":"NOT_FOUND","detail":"Not found"}`,
+		},
+		"not found": {
+			method:       http.MethodGet,
+			path:         "/orders",
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"code":"NOT_FOUND","detail":"Not found"}`,
+		},
+		"method not allowed": {
+			method:       http.MethodPost,
+			path:         "/users/123",
+			expectedCode: http.StatusMethodNotAllowed,
+			expectedBody: `{"code":"METHOD_NOT_ALLOWED","detail":"Method not allowed"}`,
+		},
+	}
+
+	lh := g8.APIGatewayProxyHandler(newTestRouter().Dispatch, g8.HandlerConfig{})
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			apitest.New().
+				Handler(adapter.GetHTTPHandlerWithContext(lh, "/{proxy+}", nil, nil)).
+				Method(tc.method).
+				URL(tc.path).
+				Expect(t).
+				Status(tc.expectedCode).
+				Body(tc.expectedBody).
+				End()
+		})
+	}
+}
+
+func TestRouter_MethodNotAllowedAllowHeader(t *testing.T) {
+	lh := g8.APIGatewayProxyHandler(newTestRouter().Dispatch, g8.HandlerConfig{})
+
+	apitest.New().
+		Handler(adapter.GetHTTPHandlerWithContext(lh, "/{proxy+}", nil, nil)).
+		Patch("/users/123").
+		Expect(t).
+		Status(http.StatusMethodNotAllowed).
+		Header("Allow", "DELETE, GET").
+		End()
+}
+
+func TestRouter_ExplicitMethodTakesPrecedenceOverAny(t *testing.T) {
+	r := g8.NewRouter()
+	r.Handle(g8.MethodAny, "/items", func(c *g8.APIGatewayProxyContext) error {
+		return c.JSON(http.StatusOK, map[string]string{"route": "any"})
+	})
+	r.Get("/items", func(c *g8.APIGatewayProxyContext) error {
+		return c.JSON(http.StatusOK, map[string]string{"route": "get"})
+	})
+	lh := g8.APIGatewayProxyHandler(r.Dispatch, g8.HandlerConfig{})
+
+	apitest.New().
+		Handler(adapter.GetHTTPHandlerWithContext(lh, "/{proxy+}", nil, nil)).
+		Get("/items").
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"route":"get"}`).
+		End()
+
+	apitest.New().
+		Handler(adapter.GetHTTPHandlerWithContext(lh, "/{proxy+}", nil, nil)).
+		Post("/items").
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"route":"any"}`).
+		End()
+}
+
+func TestRouter_LambdaAdapter(t *testing.T) {
+	l := g8.LambdaHandler{
+		Handler:     newTestRouter().Dispatch,
+		Method:      g8.MethodAny,
+		PathPattern: "/{proxy+}",
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/users/123", nil)
+	g8.LambdaAdapter(l)(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"route":"get","id":"123"}`, w.Body.String())
+}
+
+func TestRouter_LambdaAdapterMethodNotAllowed(t *testing.T) {
+	l := g8.LambdaHandler{
+		Handler:     newTestRouter().Dispatch,
+		Method:      g8.MethodAny,
+		PathPattern: "/{proxy+}",
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPatch, "/users/123", nil)
+	g8.LambdaAdapter(l)(w, r)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "DELETE, GET", w.Header().Get("Allow"))
+	assert.Equal(t, `{"code":"METHOD_NOT_ALLOWED","detail":"Method not allowed"}`, w.Body.String())
+}
+
+func TestLambdaAdapter_GreedyPathParam(t *testing.T) {
+	l := g8.LambdaHandler{
+		Handler: func(c *g8.APIGatewayProxyContext) error {
+			return c.JSON(http.StatusOK, c.Request.PathParameters)
+		},
+		Method:      http.MethodGet,
+		PathPattern: "/static/{proxy+}",
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/static/css/site.css", nil)
+	g8.LambdaAdapter(l)(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"proxy":"css/site.css"}`, w.Body.String())
+}
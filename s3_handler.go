@@ -22,6 +22,7 @@ type S3Context struct {
 type S3HandlerFunc func(c *S3Context) error
 
 func S3Handler(h S3HandlerFunc, conf HandlerConfig) func(context.Context, events.S3Event) error {
+	h = chainMiddleware(h, conf.S3Middleware)
 	return func(ctx context.Context, e events.S3Event) error {
 		for _, record := range e.Records {
 			correlationID := uuid.New().String()
@@ -32,7 +33,7 @@ func S3Handler(h S3HandlerFunc, conf HandlerConfig) func(context.Context, events
 				Logger()
 
 			c := &S3Context{
-				Context:       ctx,
+				Context:       ContextWithCorrelationID(ctx, correlationID),
 				EventRecord:   record,
 				Logger:        logger,
 				NewRelicTx:    newrelic.FromContext(ctx),
@@ -44,7 +45,7 @@ func S3Handler(h S3HandlerFunc, conf HandlerConfig) func(context.Context, events
 			c.AddNewRelicAttribute("correlationID", correlationID)
 			c.AddNewRelicAttribute("s3EventSource", record.EventSource)
 
-			if err := h(c); err != nil {
+			if err := callHandler(c.NewRelicTx, func() error { return h(c) }); err != nil {
 				logUnhandledError(c.Logger, err)
 				return err
 			}
@@ -96,3 +96,18 @@ func TestS3Handler_HandlerError(t *testing.T) {
 	assert.Equal(t, assert.AnError, err)
 	assert.Equal(t, 1, timesCalled)
 }
+
+func TestS3Handler_HandlerPanic(t *testing.T) {
+	handlerFunc := func(c *g8.S3Context) error {
+		panic("something went badly wrong")
+	}
+
+	h := g8.S3Handler(handlerFunc, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+	})
+	err := h(context.Background(), events.S3Event{
+		Records: []events.S3EventRecord{{}},
+	})
+
+	assert.EqualError(t, err, "recovered panic: something went badly wrong")
+}
@@ -0,0 +1,48 @@
+package g8
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/newrelic/go-agent/_integrations/nrlambda"
+)
+
+// SQSBatchHandler processes every record in the batch, rather than returning on the first error like SQSHandler,
+// and reports the records which failed so that only they are retried. The event source mapping must have
+// ReportBatchItemFailures enabled, otherwise the whole batch is deleted from the queue. Records which haven't been
+// started when the context's deadline is reached are reported as failures.
+func SQSBatchHandler(
+	h SQSHandlerFunc,
+	conf HandlerConfig,
+) func(context.Context, events.SQSEvent) (events.SQSEventResponse, error) {
+	h = conf.SQSIdempotency.wrap(chainMiddleware(h, conf.SQSMiddleware))
+	return func(ctx context.Context, e events.SQSEvent) (events.SQSEventResponse, error) {
+		var res events.SQSEventResponse
+		for i, err := range processSQSRecords(ctx, e.Records, h, conf, false) {
+			if err != nil {
+				res.BatchItemFailures = append(res.BatchItemFailures, events.SQSBatchItemFailure{
+					ItemIdentifier: e.Records[i].MessageId,
+				})
+			}
+		}
+		return res, nil
+	}
+}
+
+func SQSBatchHandlerWithNewRelic(h SQSHandlerFunc, conf HandlerConfig) lambda.Handler {
+	return nrlambda.Wrap(SQSBatchHandler(h, conf), conf.NewRelicApp)
+}
+
+// SQSBatchHandlerOf is an SQSBatchHandler which binds and validates each message before calling the handler,
+// reporting the messages which can't be bound as failures
+func SQSBatchHandlerOf[T any](
+	h SQSHandlerFuncOf[T],
+	conf HandlerConfig,
+) func(context.Context, events.SQSEvent) (events.SQSEventResponse, error) {
+	return SQSBatchHandler(h.bind, conf)
+}
+
+func SQSBatchHandlerOfWithNewRelic[T any](h SQSHandlerFuncOf[T], conf HandlerConfig) lambda.Handler {
+	return nrlambda.Wrap(SQSBatchHandlerOf(h, conf), conf.NewRelicApp)
+}
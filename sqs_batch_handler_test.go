@@ -0,0 +1,262 @@
+package g8_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JSainsburyPLC/g8"
+)
+
+func TestSQSBatchHandler_PartialFailures(t *testing.T) {
+	var processed []string
+	handlerFunc := func(c *g8.SQSContext) error {
+		var data map[string]string
+		if err := c.Bind(&data); err != nil {
+			return err
+		}
+		switch data["key"] {
+		case "error":
+			return assert.AnError
+		case "panic":
+			panic("boom")
+		}
+		processed = append(processed, c.Message.MessageId)
+		return nil
+	}
+
+	var logBuf bytes.Buffer
+	h := g8.SQSBatchHandler(handlerFunc, g8.HandlerConfig{
+		Logger: zerolog.New(&logBuf),
+	})
+	res, err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "1", Body: `{"data": {"key": "ok"}, "meta": {"correlation_id": "corr-1"}}`},
+		{MessageId: "2", Body: `{"data": {"key": "error"}, "meta": {"correlation_id": "corr-2"}}`},
+		{MessageId: "3", Body: `not valid json`},
+		{MessageId: "4", Body: `{"key": "panic"}`},
+		{MessageId: "5", Body: `{"key": "ok"}`},
+	}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1", "5"}, processed)
+	assert.Equal(t, events.SQSEventResponse{BatchItemFailures: []events.SQSBatchItemFailure{
+		{ItemIdentifier: "2"},
+		{ItemIdentifier: "3"},
+		{ItemIdentifier: "4"},
+	}}, res)
+
+	logs := strings.Split(strings.TrimSpace(logBuf.String()), "\n")
+	assert.Len(t, logs, 3)
+	assert.Equal(t, "Unhandled error", jsonPath("$.message", []byte(logs[0])))
+	assert.Equal(t, "corr-2", jsonPath("$.correlation_id", []byte(logs[0])))
+	assert.Equal(t, "2", jsonPath("$.sqs_message_id", []byte(logs[0])))
+	assert.Equal(t, "3", jsonPath("$.sqs_message_id", []byte(logs[1])))
+	assert.Equal(t, "4", jsonPath("$.sqs_message_id", []byte(logs[2])))
+}
+
+func TestSQSBatchHandler_AllSucceed(t *testing.T) {
+	timesCalled := 0
+	h := g8.SQSBatchHandler(func(c *g8.SQSContext) error {
+		timesCalled++
+		return nil
+	}, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+	})
+	res, err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "1", Body: `{}`},
+		{MessageId: "2", Body: `{}`},
+	}})
+
+	assert.Nil(t, err)
+	assert.Empty(t, res.BatchItemFailures)
+	assert.Equal(t, 2, timesCalled)
+}
+
+func TestSQSBatchHandler_Concurrency(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	started := make(chan string, 10)
+	release := make(chan struct{})
+	h := g8.SQSBatchHandler(func(c *g8.SQSContext) error {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		started <- c.Message.MessageId
+		<-release
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		if c.Message.MessageId == "3" {
+			return assert.AnError
+		}
+		return nil
+	}, g8.HandlerConfig{
+		Logger:         zerolog.New(io.Discard),
+		SQSConcurrency: 3,
+	})
+
+	var records []events.SQSMessage
+	for i := 1; i <= 10; i++ {
+		records = append(records, events.SQSMessage{MessageId: strconv.Itoa(i), Body: `{}`})
+	}
+	done := make(chan events.SQSEventResponse)
+	go func() {
+		res, err := h(context.Background(), events.SQSEvent{Records: records})
+		assert.Nil(t, err)
+		done <- res
+	}()
+
+	// the first three records are held in flight, and no more are started until they're released
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+	mu.Lock()
+	assert.Equal(t, 3, inFlight)
+	mu.Unlock()
+	assert.Empty(t, started)
+	close(release)
+	res := <-done
+
+	assert.Equal(t, 3, maxInFlight)
+	assert.Len(t, started, 7)
+	assert.Equal(t, []events.SQSBatchItemFailure{{ItemIdentifier: "3"}}, res.BatchItemFailures)
+}
+
+func TestSQSBatchHandler_Deadline(t *testing.T) {
+	// records can only be started in the first second, and the first record is processed until after then
+	deadline := time.Now().Add(time.Minute)
+	margin := time.Minute - time.Second
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	var processed []string
+	h := g8.SQSBatchHandler(func(c *g8.SQSContext) error {
+		processed = append(processed, c.Message.MessageId)
+		// the handler keeps the full time left
+		d, _ := c.Context.Deadline()
+		assert.True(t, deadline.Equal(d))
+		time.Sleep(time.Until(deadline.Add(-margin)))
+		return nil
+	}, g8.HandlerConfig{
+		Logger:            zerolog.New(io.Discard),
+		SQSDeadlineMargin: margin,
+	})
+	res, err := h(ctx, events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "1", Body: `{}`},
+		{MessageId: "2", Body: `{}`},
+		{MessageId: "3", Body: `{}`},
+	}})
+
+	assert.Nil(t, err)
+	assert.NoError(t, ctx.Err())
+	assert.Equal(t, []string{"1"}, processed)
+	assert.Equal(t, []events.SQSBatchItemFailure{{ItemIdentifier: "2"}, {ItemIdentifier: "3"}}, res.BatchItemFailures)
+}
+
+func TestSQSBatchHandler_DeadlineConcurrency(t *testing.T) {
+	deadline := time.Now().Add(time.Minute)
+	margin := time.Minute - time.Second
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	var processed int32
+	h := g8.SQSBatchHandler(func(c *g8.SQSContext) error {
+		atomic.AddInt32(&processed, 1)
+		time.Sleep(time.Until(deadline.Add(-margin)))
+		return nil
+	}, g8.HandlerConfig{
+		Logger:            zerolog.New(io.Discard),
+		SQSConcurrency:    2,
+		SQSDeadlineMargin: margin,
+	})
+
+	var records []events.SQSMessage
+	for i := 1; i <= 6; i++ {
+		records = append(records, events.SQSMessage{MessageId: strconv.Itoa(i), Body: `{}`})
+	}
+	res, err := h(ctx, events.SQSEvent{Records: records})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&processed))
+	assert.Len(t, res.BatchItemFailures, 4)
+}
+
+func TestSQSHandler_ConcurrencyStopsOnError(t *testing.T) {
+	var processed int32
+	h := g8.SQSHandler(func(c *g8.SQSContext) error {
+		atomic.AddInt32(&processed, 1)
+		if c.Message.MessageId == "1" {
+			return assert.AnError
+		}
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}, g8.HandlerConfig{
+		Logger:         zerolog.New(io.Discard),
+		SQSConcurrency: 2,
+	})
+
+	var records []events.SQSMessage
+	for i := 1; i <= 10; i++ {
+		records = append(records, events.SQSMessage{MessageId: strconv.Itoa(i), Body: `{}`})
+	}
+	err := h(context.Background(), events.SQSEvent{Records: records})
+
+	assert.Equal(t, assert.AnError, err)
+	assert.Less(t, atomic.LoadInt32(&processed), int32(10))
+}
+
+func TestSQSBatchHandler_FIFO(t *testing.T) {
+	var mu sync.Mutex
+	processed := make(map[string][]string)
+	h := g8.SQSBatchHandler(func(c *g8.SQSContext) error {
+		group := c.Message.Attributes["MessageGroupId"]
+		mu.Lock()
+		processed[group] = append(processed[group], c.Message.MessageId)
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+		if c.Message.MessageId == "3" {
+			return assert.AnError
+		}
+		return nil
+	}, g8.HandlerConfig{
+		Logger:         zerolog.New(io.Discard),
+		SQSConcurrency: 2,
+		SQSFIFO:        true,
+	})
+
+	record := func(id, group string) events.SQSMessage {
+		return events.SQSMessage{MessageId: id, Body: `{}`, Attributes: map[string]string{"MessageGroupId": group}}
+	}
+	res, err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		record("1", "a"),
+		record("2", "b"),
+		record("3", "a"),
+		record("4", "b"),
+		record("5", "a"),
+		record("6", "c"),
+	}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]string{
+		"a": {"1", "3"},
+		"b": {"2", "4"},
+		"c": {"6"},
+	}, processed)
+	assert.Equal(t, []events.SQSBatchItemFailure{{ItemIdentifier: "3"}, {ItemIdentifier: "5"}}, res.BatchItemFailures)
+}
@@ -3,12 +3,15 @@ package g8
 import (
 	"context"
 	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/google/uuid"
 	newrelic "github.com/newrelic/go-agent"
 	"github.com/newrelic/go-agent/_integrations/nrlambda"
+	"github.com/rotisserie/eris"
 	"github.com/rs/zerolog"
 )
 
@@ -18,6 +21,14 @@ type SQSContext struct {
 	Logger        zerolog.Logger
 	NewRelicTx    newrelic.Transaction
 	CorrelationID string
+	// Meta is the meta data of messages sent in the SQSMessageEnvelope, which is empty for other messages
+	Meta SQSMessageMeta
+	// RawBody is the body of the message as it was received, before the envelope or any SNS notification or
+	// EventBridge event around it was removed from Message.Body
+	RawBody   string
+	validator Validator
+
+	idempotencyResult []byte
 }
 
 type SQSHandlerFunc func(c *SQSContext) error
@@ -27,43 +38,63 @@ type SQSMessageEnvelope struct {
 	Meta *SQSMessageMeta `json:"meta"`
 }
 
+// SQSMessageMeta is the meta data of a message. Type and SchemaVersion identify the data's schema, e.g. for
+// SQSTypeRoutes, and CausationID is the ID of the message which caused it to be sent.
 type SQSMessageMeta struct {
-	CorrelationID string `json:"correlation_id"`
+	CorrelationID string    `json:"correlation_id"`
+	CausationID   string    `json:"causation_id,omitempty"`
+	Type          string    `json:"type,omitempty"`
+	SchemaVersion int       `json:"schema_version,omitempty"`
+	Producer      string    `json:"producer,omitempty"`
+	OccurredAt    time.Time `json:"occurred_at"`
+	PublishedAt   time.Time `json:"published_at"`
 }
 
-func SQSHandler(h SQSHandlerFunc, conf HandlerConfig) func(context.Context, events.SQSEvent) error {
-	return func(ctx context.Context, e events.SQSEvent) error {
-		for _, record := range e.Records {
-			// parse the envelope and get the meta data if available
-			// the body should then be updated with the inner message
-			// data for when the data is bound.
-			meta, dataBytes := parseRawMessage([]byte(record.Body))
-			record.Body = string(dataBytes)
-
-			correlationID := getCorrelationIDSQS(meta)
-
-			logger := configureLogger(conf).
-				Str("correlation_id", correlationID).
-				Str("sqs_event_source", record.EventSource).
-				Str("sqs_message_id", record.MessageId).
-				Logger()
-
-			c := &SQSContext{
-				Context:       ctx,
-				Message:       record,
-				Logger:        logger,
-				NewRelicTx:    newrelic.FromContext(ctx),
-				CorrelationID: correlationID,
-			}
+// MarshalJSON omits the timestamps which aren't set
+func (m SQSMessageMeta) MarshalJSON() ([]byte, error) {
+	type meta SQSMessageMeta
+	v := struct {
+		meta
+		OccurredAt  *time.Time `json:"occurred_at,omitempty"`
+		PublishedAt *time.Time `json:"published_at,omitempty"`
+	}{meta: meta(m)}
+	if !m.OccurredAt.IsZero() {
+		v.OccurredAt = &m.OccurredAt
+	}
+	if !m.PublishedAt.IsZero() {
+		v.PublishedAt = &m.PublishedAt
+	}
+	return json.Marshal(v)
+}
 
-			c.AddNewRelicAttribute("functionName", conf.FunctionName)
-			c.AddNewRelicAttribute("sqsEventSource", record.EventSource)
-			c.AddNewRelicAttribute("sqsMessageID", record.MessageId)
-			c.AddNewRelicAttribute("correlationID", correlationID)
-			c.AddNewRelicAttribute("buildVersion", conf.BuildVersion)
+// logFields adds the meta data which is set to the logger
+func (m SQSMessageMeta) logFields(l zerolog.Context) zerolog.Context {
+	if m.CausationID != "" {
+		l = l.Str("causation_id", m.CausationID)
+	}
+	if m.Type != "" {
+		l = l.Str("message_type", m.Type)
+	}
+	if m.SchemaVersion != 0 {
+		l = l.Int("schema_version", m.SchemaVersion)
+	}
+	if m.Producer != "" {
+		l = l.Str("producer", m.Producer)
+	}
+	if !m.OccurredAt.IsZero() {
+		l = l.Time("occurred_at", m.OccurredAt)
+	}
+	if !m.PublishedAt.IsZero() {
+		l = l.Time("published_at", m.PublishedAt)
+	}
+	return l
+}
 
-			if err := h(c); err != nil {
-				logUnhandledError(c.Logger, err)
+func SQSHandler(h SQSHandlerFunc, conf HandlerConfig) func(context.Context, events.SQSEvent) error {
+	h = conf.SQSIdempotency.wrap(chainMiddleware(h, conf.SQSMiddleware))
+	return func(ctx context.Context, e events.SQSEvent) error {
+		for _, err := range processSQSRecords(ctx, e.Records, h, conf, true) {
+			if err != nil {
 				return err
 			}
 		}
@@ -75,6 +106,220 @@ func SQSHandlerWithNewRelic(h SQSHandlerFunc, conf HandlerConfig) lambda.Handler
 	return nrlambda.Wrap(SQSHandler(h, conf), conf.NewRelicApp)
 }
 
+// SQSHandlerFuncOf is passed each message bound to T
+type SQSHandlerFuncOf[T any] func(c *SQSContext, msg T) error
+
+// SQSHandlerOf is an SQSHandler which binds and validates each message before calling the handler. Messages which
+// can't be bound fail with ErrInvalidBody or a validation error, like handler errors.
+func SQSHandlerOf[T any](h SQSHandlerFuncOf[T], conf HandlerConfig) func(context.Context, events.SQSEvent) error {
+	return SQSHandler(h.bind, conf)
+}
+
+func SQSHandlerOfWithNewRelic[T any](h SQSHandlerFuncOf[T], conf HandlerConfig) lambda.Handler {
+	return nrlambda.Wrap(SQSHandlerOf(h, conf), conf.NewRelicApp)
+}
+
+func (h SQSHandlerFuncOf[T]) bind(c *SQSContext) error {
+	var msg T
+	if err := bindJSON([]byte(c.Message.Body), &msg, c.validator); err != nil {
+		return err
+	}
+	return h(c, msg)
+}
+
+func newSQSContext(ctx context.Context, record events.SQSMessage, conf HandlerConfig) *SQSContext {
+	rawBody := record.Body
+	// SNS notifications and EventBridge events are unwrapped to find the correlation ID of the published message,
+	// but only replace the body when it's configured
+	body := []byte(rawBody)
+	published, snsAttributes := unwrapSQSBody(body)
+	if conf.SQSUnwrapBody {
+		body = published
+	}
+
+	// parse the envelope and get the meta data if available
+	// the body should then be updated with the inner message
+	// data for when the data is bound.
+	meta, dataBytes := parseRawMessage(body)
+	record.Body = string(dataBytes)
+
+	// when the body isn't unwrapped the meta data still comes from the envelope of the published message
+	if meta == nil && !conf.SQSUnwrapBody {
+		meta, _ = parseRawMessage(published)
+	}
+	correlationID := getCorrelationIDSQS(meta, snsAttributes, record.MessageAttributes, conf.SQSCorrelationIDAttributes)
+
+	var m SQSMessageMeta
+	if meta != nil {
+		m = *meta
+	}
+
+	logCtx := configureLogger(conf).
+		Str("correlation_id", correlationID).
+		Str("sqs_event_source", record.EventSource).
+		Str("sqs_message_id", record.MessageId)
+	logger := m.logFields(logCtx).Logger()
+
+	c := &SQSContext{
+		Context:       ContextWithCorrelationID(ctx, correlationID),
+		Message:       record,
+		Logger:        logger,
+		NewRelicTx:    newrelic.FromContext(ctx),
+		CorrelationID: correlationID,
+		Meta:          m,
+		RawBody:       rawBody,
+		validator:     conf.Validator,
+	}
+
+	c.AddNewRelicAttribute("functionName", conf.FunctionName)
+	c.AddNewRelicAttribute("sqsEventSource", record.EventSource)
+	c.AddNewRelicAttribute("sqsMessageID", record.MessageId)
+	c.AddNewRelicAttribute("correlationID", correlationID)
+	c.AddNewRelicAttribute("buildVersion", conf.BuildVersion)
+	if m.Type != "" {
+		c.AddNewRelicAttribute("messageType", m.Type)
+	}
+
+	return c
+}
+
+// maxDefaultSQSDeadlineMargin caps the default margin, a tenth of the time left, used when
+// HandlerConfig.SQSDeadlineMargin isn't set
+const maxDefaultSQSDeadlineMargin = 5 * time.Second
+
+// processSQSRecords calls the handler for each record, with up to conf.SQSConcurrency records processed at the same
+// time, and returns the error for each record. Records aren't started once the context is done or within
+// conf.SQSDeadlineMargin of its deadline, leaving the context's error as theirs, or once a record has failed when
+// stopOnError is set, leaving theirs nil.
+//
+// When conf.SQSFIFO is set the records of each message group are processed in order, one at a time, and the records
+// following a failed record in its group aren't started so that they're retried in order.
+func processSQSRecords(
+	ctx context.Context,
+	records []events.SQSMessage,
+	h SQSHandlerFunc,
+	conf HandlerConfig,
+	stopOnError bool,
+) []error {
+	concurrency := conf.SQSConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// records aren't started once stopCtx is done, while those already started keep the full time left
+	stopCtx := ctx
+	var stopAt time.Time
+	if deadline, ok := ctx.Deadline(); ok {
+		margin := conf.SQSDeadlineMargin
+		if margin <= 0 {
+			margin = time.Until(deadline) / 10
+			if margin > maxDefaultSQSDeadlineMargin {
+				margin = maxDefaultSQSDeadlineMargin
+			}
+		}
+		stopAt = deadline.Add(-margin)
+		var cancel context.CancelFunc
+		stopCtx, cancel = context.WithDeadline(ctx, stopAt)
+		defer cancel()
+	}
+	// stopped checks the clock as well as stopCtx, which is only done once its timer has fired
+	stopped := func() error {
+		if !stopAt.IsZero() && !time.Now().Before(stopAt) {
+			return context.DeadlineExceeded
+		}
+		return stopCtx.Err()
+	}
+
+	errs := make([]error, len(records))
+	workers := make(chan struct{}, concurrency)
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+
+	// process runs the records in order, skipping the rest after a failure
+	process := func(ctx context.Context, indexes []int) {
+		for n, i := range indexes {
+			if err := stopped(); err != nil {
+				skipSQSRecords(records, indexes[n:], errs, conf, eris.Wrap(err, "sqs message not processed"))
+				return
+			}
+
+			c := newSQSContext(ctx, records[i], conf)
+			segment := newrelic.StartSegment(c.NewRelicTx, "SQS message")
+			err := callHandler(c.NewRelicTx, func() error { return h(c) })
+			segment.End()
+			if err != nil {
+				logUnhandledError(c.Logger, err)
+				errs[i] = err
+				failed.Store(true)
+				groupErr := eris.Wrapf(err, "earlier sqs message %s in group failed", records[i].MessageId)
+				skipSQSRecords(records, indexes[n+1:], errs, conf, groupErr)
+				return
+			}
+		}
+	}
+
+	for _, indexes := range groupSQSRecords(records, conf.SQSFIFO) {
+		select {
+		case workers <- struct{}{}:
+		case <-stopCtx.Done():
+		}
+		if err := stopped(); err != nil {
+			skipSQSRecords(records, indexes, errs, conf, eris.Wrap(err, "sqs message not processed"))
+			continue
+		}
+		if stopOnError && failed.Load() {
+			<-workers
+			break
+		}
+
+		wg.Add(1)
+		go func(indexes []int) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			// the transaction must only be shared between goroutines using NewGoroutine
+			groupCtx := ctx
+			if txn := newrelic.FromContext(ctx); txn != nil && concurrency > 1 {
+				groupCtx = newrelic.NewContext(ctx, txn.NewGoroutine())
+			}
+			process(groupCtx, indexes)
+		}(indexes)
+	}
+	wg.Wait()
+	return errs
+}
+
+// groupSQSRecords returns the indexes of the records which must be processed in order, one at a time. Without fifo
+// every record is in its own group, while with fifo the records are grouped by their MessageGroupId attribute.
+func groupSQSRecords(records []events.SQSMessage, fifo bool) [][]int {
+	var groups [][]int
+	positions := make(map[string]int)
+	for i, record := range records {
+		if !fifo {
+			groups = append(groups, []int{i})
+			continue
+		}
+		id := record.Attributes["MessageGroupId"]
+		pos, ok := positions[id]
+		if !ok {
+			pos = len(groups)
+			positions[id] = pos
+			groups = append(groups, nil)
+		}
+		groups[pos] = append(groups[pos], i)
+	}
+	return groups
+}
+
+func skipSQSRecords(records []events.SQSMessage, indexes []int, errs []error, conf HandlerConfig, err error) {
+	for _, i := range indexes {
+		errs[i] = err
+		logger := configureLogger(conf).Str("sqs_message_id", records[i].MessageId).Logger()
+		logger.Warn().Err(err).Msg("SQS message not processed")
+	}
+}
+
 func (c *SQSContext) AddNewRelicAttribute(key string, val interface{}) {
 	if c.NewRelicTx == nil {
 		return
@@ -85,18 +330,7 @@ func (c *SQSContext) AddNewRelicAttribute(key string, val interface{}) {
 }
 
 func (c *SQSContext) Bind(v interface{}) error {
-	if err := json.Unmarshal([]byte(c.Message.Body), v); err != nil {
-		return err
-	}
-
-	if validatable, ok := v.(Validatable); ok {
-		err := validatable.Validate()
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return bindJSON([]byte(c.Message.Body), v, c.validator)
 }
 
 func parseRawMessage(body []byte) (*SQSMessageMeta, []byte) {
@@ -121,10 +355,3 @@ func parseRawMessage(body []byte) (*SQSMessageMeta, []byte) {
 
 	return envelope.Meta, b
 }
-
-func getCorrelationIDSQS(m *SQSMessageMeta) string {
-	if m == nil || m.CorrelationID == "" {
-		return uuid.New().String()
-	}
-	return m.CorrelationID
-}
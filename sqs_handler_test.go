@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"testing"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/JSainsburyPLC/g8"
 )
@@ -182,8 +185,12 @@ func TestSQSHandler_InvalidJSON(t *testing.T) {
 		},
 	}})
 
-	assert.NotNil(t, err)
-	assert.Equal(t, "invalid character 'o' in literal null (expecting 'u')", err.Error())
+	assert.Equal(t, g8.Err{
+		Status:  http.StatusBadRequest,
+		Code:    "INVALID_REQUEST_BODY",
+		Detail:  "Invalid request body",
+		Details: &g8.ErrDetails{Meta: map[string]interface{}{"offset": int64(2)}},
+	}, err)
 	assert.Equal(t, 1, timesCalled)
 }
 
@@ -209,3 +216,314 @@ func TestSQSHandler_HandlerError(t *testing.T) {
 	assert.Equal(t, assert.AnError, err)
 	assert.Equal(t, 1, timesCalled)
 }
+
+func TestSQSHandler_HandlerPanic(t *testing.T) {
+	handlerFunc := func(c *g8.SQSContext) error {
+		panic(assert.AnError)
+	}
+
+	h := g8.SQSHandler(handlerFunc, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+	})
+	err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{
+			Body: `{"key1": "value1"}`,
+		},
+	}})
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Contains(t, err.Error(), "recovered panic")
+}
+
+func TestSQSHandler_CorrelationIDFromMessageAttributes(t *testing.T) {
+	var correlationID string
+	h := g8.SQSHandler(func(c *g8.SQSContext) error {
+		correlationID = c.CorrelationID
+		return nil
+	}, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+	})
+	err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{{
+		Body: `{"key1": "value1"}`,
+		MessageAttributes: map[string]events.SQSMessageAttribute{
+			"Correlation-Id": {DataType: "String", StringValue: aws.String("abcdef")},
+		},
+	}}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "abcdef", correlationID)
+}
+
+func TestSQSHandler_CorrelationIDCustomAttribute(t *testing.T) {
+	var correlationID string
+	h := g8.SQSHandler(func(c *g8.SQSContext) error {
+		correlationID = c.CorrelationID
+		return nil
+	}, g8.HandlerConfig{
+		Logger:                     zerolog.New(io.Discard),
+		SQSCorrelationIDAttributes: []string{"trace_id"},
+	})
+	err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{{
+		Body: `{"key1": "value1"}`,
+		MessageAttributes: map[string]events.SQSMessageAttribute{
+			"correlation_id": {DataType: "String", StringValue: aws.String("ignored")},
+			"trace_id":       {DataType: "String", StringValue: aws.String("abcdef")},
+		},
+	}}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "abcdef", correlationID)
+}
+
+func TestSQSHandler_SNSNotification(t *testing.T) {
+	tests := []struct {
+		name                  string
+		message               string
+		messageAttributes     string
+		expectedCorrelationID string
+	}{
+		{
+			name:                  "enveloped message",
+			message:               `{\"data\": {\"key1\": \"value1\"}, \"meta\": {\"correlation_id\": \"from-meta\"}}`,
+			messageAttributes:     `{"correlation_id": {"Type": "String", "Value": "from-attribute"}}`,
+			expectedCorrelationID: "from-meta",
+		},
+		{
+			name:                  "message attribute",
+			message:               `{\"key1\": \"value1\"}`,
+			messageAttributes:     `{"correlation_id": {"Type": "String", "Value": "from-attribute"}}`,
+			expectedCorrelationID: "from-attribute",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data map[string]string
+			var correlationID string
+			h := g8.SQSHandler(func(c *g8.SQSContext) error {
+				correlationID = c.CorrelationID
+				return c.Bind(&data)
+			}, g8.HandlerConfig{
+				Logger:        zerolog.New(io.Discard),
+				SQSUnwrapBody: true,
+			})
+			err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{{
+				Body: snsNotification(tt.message, tt.messageAttributes),
+			}}})
+
+			assert.Nil(t, err)
+			assert.Equal(t, map[string]string{"key1": "value1"}, data)
+			assert.Equal(t, tt.expectedCorrelationID, correlationID)
+		})
+	}
+}
+
+func TestSQSHandler_SNSRawMessageDelivery(t *testing.T) {
+	var data map[string]string
+	var correlationID string
+	h := g8.SQSHandler(func(c *g8.SQSContext) error {
+		correlationID = c.CorrelationID
+		return c.Bind(&data)
+	}, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+	})
+	err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{{
+		Body: `{"Type": "Notification", "key1": "value1"}`,
+		MessageAttributes: map[string]events.SQSMessageAttribute{
+			"correlation_id": {DataType: "String", StringValue: aws.String("abcdef")},
+		},
+	}}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"Type": "Notification", "key1": "value1"}, data)
+	assert.Equal(t, "abcdef", correlationID)
+}
+
+const eventBridgeEvent = `{
+  "version": "0",
+  "id": "6a7e8feb-b491-4cf7-a9f1-bf3703467718",
+  "detail-type": "OrderPlaced",
+  "source": "orders",
+  "account": "123456789012",
+  "time": "2023-01-01T00:00:00Z",
+  "region": "eu-west-1",
+  "resources": [],
+  "detail": {"data": {"key1": "value1"}, "meta": {"correlation_id": "abcdef"}}
+}`
+
+func TestSQSHandler_EventBridgeEvent(t *testing.T) {
+	var data map[string]string
+	var correlationID string
+	h := g8.SQSHandler(func(c *g8.SQSContext) error {
+		correlationID = c.CorrelationID
+		return c.Bind(&data)
+	}, g8.HandlerConfig{
+		Logger:        zerolog.New(io.Discard),
+		SQSUnwrapBody: true,
+	})
+	err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{{Body: eventBridgeEvent}}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"key1": "value1"}, data)
+	assert.Equal(t, "abcdef", correlationID)
+}
+
+func TestSQSHandler_WrappedBodyKeptByDefault(t *testing.T) {
+	tests := []struct {
+		name                  string
+		body                  string
+		expectedCorrelationID string
+	}{
+		{
+			name: "sns notification",
+			body: snsNotification(
+				`{\"key1\": \"value1\"}`,
+				`{"correlation_id": {"Type": "String", "Value": "abcdef"}}`,
+			),
+			expectedCorrelationID: "abcdef",
+		},
+		{
+			name:                  "eventbridge event",
+			body:                  eventBridgeEvent,
+			expectedCorrelationID: "abcdef",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c *g8.SQSContext
+			h := g8.SQSHandler(func(ctx *g8.SQSContext) error {
+				c = ctx
+				return nil
+			}, g8.HandlerConfig{
+				Logger: zerolog.New(io.Discard),
+			})
+			err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{{Body: tt.body}}})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCorrelationID, c.CorrelationID)
+			assert.Equal(t, tt.body, c.Message.Body)
+			assert.Equal(t, tt.body, c.RawBody)
+		})
+	}
+}
+
+func TestSQSHandler_WrappedEnvelopeMeta(t *testing.T) {
+	body := snsNotification(
+		`{\"data\": {\"order_id\": \"1\"}, \"meta\": {\"correlation_id\": \"abcdef\", `+
+			`\"type\": \"OrderPlaced\", \"schema_version\": 2, \"producer\": \"orders\"}}`,
+		`{}`,
+	)
+	var c *g8.SQSContext
+	routes := g8.SQSTypeRoutes{
+		g8.SQSRouteKey("OrderPlaced", 2): func(ctx *g8.SQSContext) error {
+			c = ctx
+			return nil
+		},
+	}
+	h := g8.SQSHandler(routes.Dispatch, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+	})
+	err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{{Body: body}}})
+
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	assert.Equal(t, "abcdef", c.CorrelationID)
+	assert.Equal(t, g8.SQSMessageMeta{
+		CorrelationID: "abcdef",
+		Type:          "OrderPlaced",
+		SchemaVersion: 2,
+		Producer:      "orders",
+	}, c.Meta)
+	assert.Equal(t, body, c.Message.Body)
+}
+
+func TestSQSHandler_UnwrapBodyIgnoresDomainMessages(t *testing.T) {
+	body := `{"detail-type": "refund", "source": "store", "detail": {"amount": 1}}`
+	var c *g8.SQSContext
+	h := g8.SQSHandler(func(ctx *g8.SQSContext) error {
+		c = ctx
+		return nil
+	}, g8.HandlerConfig{
+		Logger:        zerolog.New(io.Discard),
+		SQSUnwrapBody: true,
+	})
+	err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{{Body: body}}})
+
+	require.NoError(t, err)
+	assert.Equal(t, body, c.Message.Body)
+}
+
+func TestSQSHandler_RawBody(t *testing.T) {
+	body := `{"data": {"key1": "value1"}, "meta": {"correlation_id": "abcdef"}}`
+	var c *g8.SQSContext
+	h := g8.SQSHandler(func(ctx *g8.SQSContext) error {
+		c = ctx
+		return nil
+	}, g8.HandlerConfig{
+		Logger: zerolog.New(io.Discard),
+	})
+	err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{{Body: body}}})
+
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"key1": "value1"}`, c.Message.Body)
+	assert.Equal(t, body, c.RawBody)
+}
+
+func snsNotification(message, messageAttributes string) string {
+	return fmt.Sprintf(`{
+  "Type": "Notification",
+  "MessageId": "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+  "TopicArn": "arn:aws:sns:us-west-2:123456789012:MyTopic",
+  "Message": "%s",
+  "Timestamp": "2023-01-01T00:00:00.000Z",
+  "MessageAttributes": %s
+}`, message, messageAttributes)
+}
+
+type orderMessage struct {
+	ID       string `json:"id" validate:"required"`
+	Quantity int    `json:"quantity" validate:"min=1"`
+}
+
+func TestSQSHandlerOf(t *testing.T) {
+	var orders []orderMessage
+	h := g8.SQSHandlerOf(func(c *g8.SQSContext, o orderMessage) error {
+		orders = append(orders, o)
+		return nil
+	}, g8.HandlerConfig{
+		Logger:    zerolog.New(io.Discard),
+		Validator: g8.TagValidator{},
+	})
+	err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{Body: `{"data": {"id": "1", "quantity": 2}, "meta": {"correlation_id": "abcdef"}}`},
+		{Body: `{"id": "2", "quantity": 1}`},
+	}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []orderMessage{{ID: "1", Quantity: 2}, {ID: "2", Quantity: 1}}, orders)
+}
+
+func TestSQSHandlerOf_BindingErrors(t *testing.T) {
+	h := g8.SQSBatchHandlerOf(func(c *g8.SQSContext, o orderMessage) error {
+		return nil
+	}, g8.HandlerConfig{
+		Logger:    zerolog.New(io.Discard),
+		Validator: g8.TagValidator{},
+	})
+	res, err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "1", Body: `{"id": "1", "quantity": 1}`},
+		{MessageId: "2", Body: `not valid json`},
+		{MessageId: "3", Body: `{"id": "3", "quantity": 0}`},
+	}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []events.SQSBatchItemFailure{{ItemIdentifier: "2"}, {ItemIdentifier: "3"}}, res.BatchItemFailures)
+
+	single := g8.SQSHandlerOf(func(c *g8.SQSContext, o orderMessage) error {
+		return nil
+	}, g8.HandlerConfig{Logger: zerolog.New(io.Discard)})
+	err = single(context.Background(), events.SQSEvent{Records: []events.SQSMessage{{Body: `not valid json`}}})
+
+	var gErr g8.Err
+	assert.ErrorAs(t, err, &gErr)
+	assert.Equal(t, "INVALID_REQUEST_BODY", gErr.Code)
+}
@@ -0,0 +1,55 @@
+package g8
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// SQSRouteDefault is the SQSTypeRoutes key of the func handling messages whose type isn't registered
+const SQSRouteDefault = "$default"
+
+var ErrUnknownMessageType = Err{
+	Status: http.StatusUnprocessableEntity,
+	Code:   "UNKNOWN_MESSAGE_TYPE",
+	Detail: "Unknown message type",
+}
+
+// SQSTypeRoutes maps the types of messages sent in the SQSMessageEnvelope to the funcs handling them, so that a
+// single SQSHandler can handle several types of message. Funcs for a specific schema version are registered with
+// the key returned by SQSRouteKey, and are used over the func registered for just the type. Messages whose type isn't
+// registered are handled by the SQSRouteDefault func, and otherwise fail with ErrUnknownMessageType.
+//
+//	g8.SQSHandler(g8.SQSTypeRoutes{
+//		"OrderPlaced":                    g8.BindSQS(handleOrderPlaced),
+//		g8.SQSRouteKey("OrderPlaced", 2): g8.BindSQS(handleOrderPlacedV2),
+//	}.Dispatch, conf)
+type SQSTypeRoutes map[string]SQSHandlerFunc
+
+// SQSRouteKey returns the SQSTypeRoutes key of a message type's schema version
+func SQSRouteKey(messageType string, schemaVersion int) string {
+	return fmt.Sprintf("%s/v%d", messageType, schemaVersion)
+}
+
+// Dispatch calls the func registered for the message's type and schema version
+func (routes SQSTypeRoutes) Dispatch(c *SQSContext) error {
+	if h, ok := routes[SQSRouteKey(c.Meta.Type, c.Meta.SchemaVersion)]; ok {
+		return h(c)
+	}
+	if h, ok := routes[c.Meta.Type]; ok && c.Meta.Type != "" {
+		return h(c)
+	}
+	if h, ok := routes[SQSRouteDefault]; ok {
+		return h(c)
+	}
+
+	return ErrUnknownMessageType.WithMeta(map[string]interface{}{
+		"type":           c.Meta.Type,
+		"schema_version": c.Meta.SchemaVersion,
+	})
+}
+
+// BindSQS returns the SQSHandlerFunc which binds and validates each message before calling the handler, e.g. for
+// SQSTypeRoutes
+func BindSQS[T any](h SQSHandlerFuncOf[T]) SQSHandlerFunc {
+	return h.bind
+}
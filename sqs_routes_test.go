@@ -0,0 +1,115 @@
+package g8_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/JSainsburyPLC/g8"
+)
+
+type orderPlaced struct {
+	OrderID string `json:"order_id"`
+}
+
+type orderPlacedV2 struct {
+	Order struct {
+		ID string `json:"id"`
+	} `json:"order"`
+}
+
+func TestSQSTypeRoutes(t *testing.T) {
+	var calls []string
+	routes := g8.SQSTypeRoutes{
+		"OrderPlaced": g8.BindSQS(func(c *g8.SQSContext, msg orderPlaced) error {
+			calls = append(calls, "v1 "+msg.OrderID)
+			return nil
+		}),
+		g8.SQSRouteKey("OrderPlaced", 2): g8.BindSQS(func(c *g8.SQSContext, msg orderPlacedV2) error {
+			calls = append(calls, "v2 "+msg.Order.ID)
+			return nil
+		}),
+		g8.SQSRouteDefault: func(c *g8.SQSContext) error {
+			calls = append(calls, "default "+c.Meta.Type)
+			return nil
+		},
+	}
+	h := g8.SQSHandler(routes.Dispatch, g8.HandlerConfig{Logger: zerolog.New(io.Discard)})
+
+	err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{Body: `{"data": {"order_id": "1"}, "meta": {"type": "OrderPlaced", "schema_version": 1}}`},
+		{Body: `{"data": {"order": {"id": "2"}}, "meta": {"type": "OrderPlaced", "schema_version": 2}}`},
+		{Body: `{"data": {"order_id": "3"}, "meta": {"type": "OrderPlaced"}}`},
+		{Body: `{"data": {}, "meta": {"type": "OrderCancelled"}}`},
+		{Body: `{"order_id": "4"}`},
+	}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v1 1", "v2 2", "v1 3", "default OrderCancelled", "default "}, calls)
+}
+
+func TestSQSTypeRoutes_UnknownType(t *testing.T) {
+	h := g8.SQSHandler(g8.SQSTypeRoutes{
+		"OrderPlaced": func(c *g8.SQSContext) error { return nil },
+	}.Dispatch, g8.HandlerConfig{Logger: zerolog.New(io.Discard)})
+
+	err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{Body: `{"data": {}, "meta": {"type": "OrderCancelled", "schema_version": 3}}`},
+	}})
+
+	var gErr g8.Err
+	require.ErrorAs(t, err, &gErr)
+	assert.Equal(t, "UNKNOWN_MESSAGE_TYPE", gErr.Code)
+	require.NotNil(t, gErr.Details)
+	assert.Equal(t, map[string]interface{}{"type": "OrderCancelled", "schema_version": 3}, gErr.Details.Meta)
+}
+
+func TestSQSHandler_Meta(t *testing.T) {
+	var meta g8.SQSMessageMeta
+	var logBuf bytes.Buffer
+	h := g8.SQSHandler(func(c *g8.SQSContext) error {
+		meta = c.Meta
+		c.Logger.Info().Msg("handled")
+		return nil
+	}, g8.HandlerConfig{Logger: zerolog.New(&logBuf)})
+
+	err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{{
+		Body: `{
+          "data": {"order_id": "1"},
+          "meta": {
+            "correlation_id": "abcdef",
+            "causation_id": "cause-1",
+            "type": "OrderPlaced",
+            "schema_version": 2,
+            "producer": "orders-api",
+            "occurred_at": "2023-01-02T03:04:05Z",
+            "published_at": "2023-01-02T03:04:06Z"
+          }
+        }`,
+	}}})
+
+	require.NoError(t, err)
+	assert.Equal(t, g8.SQSMessageMeta{
+		CorrelationID: "abcdef",
+		CausationID:   "cause-1",
+		Type:          "OrderPlaced",
+		SchemaVersion: 2,
+		Producer:      "orders-api",
+		OccurredAt:    time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		PublishedAt:   time.Date(2023, 1, 2, 3, 4, 6, 0, time.UTC),
+	}, meta)
+
+	log := logBuf.Bytes()
+	assert.Equal(t, "cause-1", jsonPath("$.causation_id", log))
+	assert.Equal(t, "OrderPlaced", jsonPath("$.message_type", log))
+	assert.Equal(t, float64(2), jsonPath("$.schema_version", log))
+	assert.Equal(t, "orders-api", jsonPath("$.producer", log))
+	assert.Equal(t, "2023-01-02T03:04:05Z", jsonPath("$.occurred_at", log))
+}
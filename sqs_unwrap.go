@@ -0,0 +1,89 @@
+package g8
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// defaultSQSCorrelationIDAttributes are the message attributes checked for the correlation ID when
+// HandlerConfig.SQSCorrelationIDAttributes isn't set
+var defaultSQSCorrelationIDAttributes = []string{"correlation_id", "correlation-id", "correlationId"}
+
+// unwrapSQSBody returns the published message when the body is an SNS notification or an EventBridge event sent to
+// the queue, along with the SNS message attributes. SNS subscriptions with raw message delivery enabled send the
+// published message as the body and its attributes as SQS message attributes, so the body is returned unchanged.
+// Every field set by SNS or EventBridge must be present, so that other messages with some of the same keys aren't
+// mistaken for them.
+func unwrapSQSBody(body []byte) ([]byte, map[string]string) {
+	var notification events.SNSEntity
+	if err := json.Unmarshal(body, &notification); err == nil &&
+		notification.Type == "Notification" &&
+		notification.MessageID != "" &&
+		strings.HasPrefix(notification.TopicArn, "arn:") &&
+		!notification.Timestamp.IsZero() {
+		return []byte(notification.Message), snsStringAttributes(notification.MessageAttributes)
+	}
+
+	var event events.CloudWatchEvent
+	if err := json.Unmarshal(body, &event); err == nil &&
+		event.Version != "" &&
+		event.ID != "" &&
+		event.DetailType != "" &&
+		event.Source != "" &&
+		event.AccountID != "" &&
+		event.Region != "" &&
+		!event.Time.IsZero() &&
+		len(event.Detail) > 0 {
+		return event.Detail, nil
+	}
+
+	return body, nil
+}
+
+// snsStringAttributes returns the values of the String attributes of an SNS notification, which are in the form
+// {"Type": "String", "Value": "..."}
+func snsStringAttributes(attributes map[string]interface{}) map[string]string {
+	values := make(map[string]string, len(attributes))
+	for name, v := range attributes {
+		attr, ok := v.(map[string]interface{})
+		if !ok || attr["Type"] != "String" {
+			continue
+		}
+		if value, ok := attr["Value"].(string); ok {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// getCorrelationIDSQS returns the correlation ID from the envelope meta, or otherwise the first of the named SNS or
+// SQS message attributes which is set, generating one when there isn't one
+func getCorrelationIDSQS(
+	m *SQSMessageMeta,
+	snsAttributes map[string]string,
+	sqsAttributes map[string]events.SQSMessageAttribute,
+	names []string,
+) string {
+	if m != nil && m.CorrelationID != "" {
+		return m.CorrelationID
+	}
+	if len(names) == 0 {
+		names = defaultSQSCorrelationIDAttributes
+	}
+	for _, name := range names {
+		for k, v := range snsAttributes {
+			if strings.EqualFold(k, name) && v != "" {
+				return v
+			}
+		}
+		for k, v := range sqsAttributes {
+			if strings.EqualFold(k, name) && v.StringValue != nil && *v.StringValue != "" {
+				return *v.StringValue
+			}
+		}
+	}
+	return uuid.New().String()
+}
@@ -2,6 +2,8 @@ package g8
 
 import (
 	"context"
+	"encoding/json"
+
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/google/uuid"
 	newrelic "github.com/newrelic/go-agent"
@@ -20,6 +22,7 @@ type StepContext struct {
 type StepHandlerFunc func(c *StepContext) (StepEvent, error)
 
 func StepHandler(h StepHandlerFunc, conf HandlerConfig) func(context.Context, StepEvent) (StepEvent, error) {
+	h = chainMiddleware(h, conf.StepMiddleware)
 	return func(ctx context.Context, e StepEvent) (StepEvent, error) {
 		correlationID := uuid.New().String()
 
@@ -29,7 +32,7 @@ func StepHandler(h StepHandlerFunc, conf HandlerConfig) func(context.Context, St
 			Logger()
 
 		c := &StepContext{
-			Context:       ctx,
+			Context:       ContextWithCorrelationID(ctx, correlationID),
 			Event:         e,
 			Logger:        logger,
 			NewRelicTx:    newrelic.FromContext(ctx),
@@ -41,8 +44,11 @@ func StepHandler(h StepHandlerFunc, conf HandlerConfig) func(context.Context, St
 		c.AddNewRelicAttribute("correlationID", correlationID)
 		c.AddNewRelicAttribute("eventSource", "step_function_event")
 
-		result, err := h(c)
-
+		var result StepEvent
+		err := callHandler(c.NewRelicTx, func() (err error) {
+			result, err = h(c)
+			return err
+		})
 		if err != nil {
 			logUnhandledError(c.Logger, err)
 		}
@@ -55,6 +61,36 @@ func StepHandlerWithNewRelic(h StepHandlerFunc, conf HandlerConfig) lambda.Handl
 	return nrlambda.Wrap(StepHandler(h, conf), conf.NewRelicApp)
 }
 
+// StepHandlerFuncOf is passed the step's input bound to In and returns its output
+type StepHandlerFuncOf[In, Out any] func(c *StepContext, in In) (Out, error)
+
+// StepHandlerOf is a StepHandler which binds and validates the input before calling the handler, failing with
+// ErrInvalidBody or a validation error when it can't be bound. The context's Event is the raw JSON input until it
+// has been bound, e.g. in middleware.
+func StepHandlerOf[In, Out any](
+	h StepHandlerFuncOf[In, Out],
+	conf HandlerConfig,
+) func(context.Context, json.RawMessage) (Out, error) {
+	sh := StepHandler(func(c *StepContext) (StepEvent, error) {
+		raw, _ := c.Event.(json.RawMessage)
+		var in In
+		if err := bindJSON(raw, &in, conf.Validator); err != nil {
+			return nil, err
+		}
+		c.Event = in
+		return h(c, in)
+	}, conf)
+	return func(ctx context.Context, e json.RawMessage) (Out, error) {
+		result, err := sh(ctx, e)
+		out, _ := result.(Out)
+		return out, err
+	}
+}
+
+func StepHandlerOfWithNewRelic[In, Out any](h StepHandlerFuncOf[In, Out], conf HandlerConfig) lambda.Handler {
+	return nrlambda.Wrap(StepHandlerOf(h, conf), conf.NewRelicApp)
+}
+
 func (c *StepContext) AddNewRelicAttribute(key string, val interface{}) {
 	if c.NewRelicTx == nil {
 		return
@@ -0,0 +1,56 @@
+package g8_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JSainsburyPLC/g8"
+)
+
+type stepInput struct {
+	OrderID string `json:"order_id" validate:"required"`
+}
+
+type stepOutput struct {
+	OrderID string `json:"order_id"`
+	Status  string `json:"status"`
+}
+
+func TestStepHandlerOf(t *testing.T) {
+	h := g8.StepHandlerOf(func(c *g8.StepContext, in stepInput) (stepOutput, error) {
+		assert.Equal(t, in, c.Event)
+		return stepOutput{OrderID: in.OrderID, Status: "shipped"}, nil
+	}, g8.HandlerConfig{
+		Logger:    zerolog.New(io.Discard),
+		Validator: g8.TagValidator{},
+	})
+
+	out, err := h(context.Background(), json.RawMessage(`{"order_id": "1"}`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, stepOutput{OrderID: "1", Status: "shipped"}, out)
+}
+
+func TestStepHandlerOf_BindingError(t *testing.T) {
+	timesCalled := 0
+	h := g8.StepHandlerOf(func(c *g8.StepContext, in stepInput) (*stepOutput, error) {
+		timesCalled++
+		return &stepOutput{}, nil
+	}, g8.HandlerConfig{
+		Logger:    zerolog.New(io.Discard),
+		Validator: g8.TagValidator{},
+	})
+
+	out, err := h(context.Background(), json.RawMessage(`{"order_id": 1}`))
+
+	var gErr g8.Err
+	assert.ErrorAs(t, err, &gErr)
+	assert.Equal(t, "INVALID_REQUEST_BODY", gErr.Code)
+	assert.Nil(t, out)
+	assert.Equal(t, 0, timesCalled)
+}
@@ -0,0 +1,297 @@
+package g8
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rotisserie/eris"
+)
+
+// Validator validates a value after it has been bound, it is set on the HandlerConfig to opt in to validation
+// of every bound request or message. Errors are handled in the same way as those returned by Validatable.
+type Validator interface {
+	Validate(v interface{}) error
+}
+
+// ValidationErrors lists every field which failed validation. It unwraps to an ErrValidationFields so it is
+// written to API responses as a bad request listing the fields.
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	parts := make([]string, len(v))
+	for i, fe := range v {
+		parts[i] = fmt.Sprintf("%s: %s", fe.Field, fe.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (v ValidationErrors) Unwrap() error {
+	return ErrValidationFields(v...)
+}
+
+// TagValidator is a Validator driven by `validate` struct tags, e.g.
+//
+//	type item struct {
+//		Name     string   `json:"name" validate:"required,max=50"`
+//		Quantity int      `json:"quantity" validate:"min=1,max=99"`
+//		Status   string   `json:"status" validate:"oneof=active inactive"`
+//		SKU      string   `json:"sku" validate:"pattern=^[0-9]{8}$"`
+//		Tags     []string `json:"tags" validate:"max=10"`
+//	}
+//
+// The supported rules are required, min and max (the length of strings, slices and maps, or the value of
+// numbers), oneof (space separated values) and pattern (a regular expression which must be the last rule).
+// Nested structs, and slices, arrays and maps of structs are validated recursively. Every failing field is
+// returned in ValidationErrors, with field paths using the json or param field names.
+type TagValidator struct{}
+
+func (TagValidator) Validate(v interface{}) error {
+	var errs ValidationErrors
+	if err := validateValue(reflect.ValueOf(v), "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validate runs the configured validator, if any, followed by the value's own Validate method
+func validate(validator Validator, v interface{}) error {
+	if validator != nil {
+		if err := validator.Validate(v); err != nil {
+			return err
+		}
+	}
+
+	if validatable, ok := v.(Validatable); ok {
+		return validatable.Validate()
+	}
+
+	return nil
+}
+
+type validationRule struct {
+	name  string
+	param string
+}
+
+func validateValue(v reflect.Value, path string, errs *ValidationErrors) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() && !(f.Anonymous && f.Type.Kind() == reflect.Struct) {
+				continue
+			}
+			fieldPath := joinFieldPath(path, fieldName(f))
+			if f.Anonymous && f.Tag.Get("json") == "" {
+				// embedded struct fields are flattened when bound
+				fieldPath = path
+			}
+			if tag, ok := f.Tag.Lookup("validate"); ok {
+				rules, err := parseValidationRules(tag)
+				if err != nil {
+					return eris.Wrapf(err, "invalid validate tag on field %s", fieldPath)
+				}
+				if err := validateField(v.Field(i), fieldPath, rules, errs); err != nil {
+					return eris.Wrapf(err, "invalid validate tag on field %s", fieldPath)
+				}
+			}
+			if err := validateValue(v.Field(i), fieldPath, errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := validateValue(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()), errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateField(v reflect.Value, path string, rules []validationRule, errs *ValidationErrors) error {
+	for _, r := range rules {
+		if r.name == "required" && isEmptyValue(v) {
+			*errs = append(*errs, FieldError{Field: path, Code: "REQUIRED", Message: "is required"})
+			return nil
+		}
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	for _, r := range rules {
+		var fe *FieldError
+		var err error
+		switch r.name {
+		case "required":
+		case "min":
+			fe, err = validateBound(v, path, r.param, false)
+		case "max":
+			fe, err = validateBound(v, path, r.param, true)
+		case "oneof":
+			fe = validateOneOf(v, path, strings.Fields(r.param))
+		case "pattern":
+			fe, err = validatePattern(v, path, r.param)
+		default:
+			err = eris.Errorf("unknown rule %s", r.name)
+		}
+		if err != nil {
+			return err
+		}
+		if fe != nil {
+			*errs = append(*errs, *fe)
+		}
+	}
+	return nil
+}
+
+func validateBound(v reflect.Value, path, param string, isMax bool) (*FieldError, error) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return nil, eris.Wrapf(err, "invalid bound %s", param)
+	}
+
+	var n float64
+	var isLength bool
+	switch v.Kind() {
+	case reflect.String:
+		n, isLength = float64(len([]rune(v.String()))), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n, isLength = float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	default:
+		return nil, eris.Errorf("bounds are not supported for %s", v.Kind())
+	}
+
+	switch {
+	case isMax && n > limit && isLength:
+		return &FieldError{Field: path, Code: "TOO_LONG", Message: fmt.Sprintf("must have a length of at most %s", param)}, nil
+	case isMax && n > limit:
+		return &FieldError{Field: path, Code: "TOO_LARGE", Message: fmt.Sprintf("must be at most %s", param)}, nil
+	case !isMax && n < limit && isLength:
+		return &FieldError{Field: path, Code: "TOO_SHORT", Message: fmt.Sprintf("must have a length of at least %s", param)}, nil
+	case !isMax && n < limit:
+		return &FieldError{Field: path, Code: "TOO_SMALL", Message: fmt.Sprintf("must be at least %s", param)}, nil
+	}
+	return nil, nil
+}
+
+func validateOneOf(v reflect.Value, path string, options []string) *FieldError {
+	value := fmt.Sprint(v.Interface())
+	for _, o := range options {
+		if value == o {
+			return nil
+		}
+	}
+	return &FieldError{Field: path, Code: "NOT_ONE_OF", Message: fmt.Sprintf("must be one of: %s", strings.Join(options, ", "))}
+}
+
+var patternCache sync.Map
+
+func validatePattern(v reflect.Value, path, pattern string) (*FieldError, error) {
+	if v.Kind() != reflect.String {
+		return nil, eris.Errorf("patterns are not supported for %s", v.Kind())
+	}
+
+	var re *regexp.Regexp
+	if cached, ok := patternCache.Load(pattern); ok {
+		re = cached.(*regexp.Regexp)
+	} else {
+		var err error
+		re, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, eris.Wrapf(err, "invalid pattern %s", pattern)
+		}
+		patternCache.Store(pattern, re)
+	}
+
+	if !re.MatchString(v.String()) {
+		return &FieldError{Field: path, Code: "PATTERN_MISMATCH", Message: fmt.Sprintf("must match the pattern %s", pattern)}, nil
+	}
+	return nil, nil
+}
+
+func parseValidationRules(tag string) ([]validationRule, error) {
+	var rules []validationRule
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(tag, "pattern=") {
+			// patterns may contain commas so must be the last rule
+			part, tag = tag, ""
+		} else {
+			part, tag, _ = strings.Cut(tag, ",")
+		}
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "" {
+			continue
+		}
+		if name != "required" && param == "" {
+			return nil, eris.Errorf("rule %s requires a parameter", name)
+		}
+		rules = append(rules, validationRule{name: name, param: param})
+	}
+	return rules, nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+// fieldName is the name of the field when bound, from its json or param tag
+func fieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name != "" && name != "-" {
+		return name
+	}
+	for _, source := range []string{"path", "query", "header"} {
+		if name := f.Tag.Get(source); name != "" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+func joinFieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,168 @@
+package g8_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	adapter "github.com/jfallis/lambda-proxy-http-adapter"
+	"github.com/rs/zerolog"
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JSainsburyPLC/g8"
+)
+
+type orderLine struct {
+	SKU      string `json:"sku" validate:"required,pattern=^[0-9]{4}$"`
+	Quantity int    `json:"quantity" validate:"min=1,max=99"`
+}
+
+type address struct {
+	Postcode string `json:"postcode" validate:"required"`
+}
+
+type order struct {
+	Reference string      `json:"reference" validate:"required,min=3,max=10"`
+	Status    string      `json:"status" validate:"oneof=pending complete"`
+	Total     *float64    `json:"total" validate:"required,min=0"`
+	Lines     []orderLine `json:"lines" validate:"required,max=2"`
+	Address   *address    `json:"address"`
+	Notes     string      `json:"-" validate:"max=5"`
+}
+
+func TestTagValidator_Validate(t *testing.T) {
+	total := 10.5
+	negative := -1.0
+
+	testCases := map[string]struct {
+		v              interface{}
+		expectedErrors g8.ValidationErrors
+	}{
+		"valid": {
+			v: order{
+				Reference: "abc",
+				Status:    "pending",
+				Total:     &total,
+				Lines:     []orderLine{{SKU: "1234", Quantity: 1}},
+				Address:   &address{Postcode: "EC1N 2HT"},
+			},
+		},
+		"valid pointer": {
+			v: &order{
+				Reference: "abcdefghij",
+				Status:    "complete",
+				Total:     &total,
+				Lines:     []orderLine{{SKU: "1234", Quantity: 99}},
+			},
+		},
+		"required": {
+			v: order{Status: "pending"},
+			expectedErrors: g8.ValidationErrors{
+				{Field: "reference", Code: "REQUIRED", Message: "is required"},
+				{Field: "total", Code: "REQUIRED", Message: "is required"},
+				{Field: "lines", Code: "REQUIRED", Message: "is required"},
+			},
+		},
+		"every failing field": {
+			v: order{
+				Reference: "ab",
+				Status:    "cancelled",
+				Total:     &negative,
+				Lines: []orderLine{
+					{SKU: "1234", Quantity: 0},
+					{SKU: "abcd", Quantity: 100},
+					{Quantity: 1},
+				},
+				Address: &address{},
+				Notes:   "too long",
+			},
+			expectedErrors: g8.ValidationErrors{
+				{Field: "reference", Code: "TOO_SHORT", Message: "must have a length of at least 3"},
+				{Field: "status", Code: "NOT_ONE_OF", Message: "must be one of: pending, complete"},
+				{Field: "total", Code: "TOO_SMALL", Message: "must be at least 0"},
+				{Field: "lines", Code: "TOO_LONG", Message: "must have a length of at most 2"},
+				{Field: "lines[0].quantity", Code: "TOO_SMALL", Message: "must be at least 1"},
+				{Field: "lines[1].sku", Code: "PATTERN_MISMATCH", Message: "must match the pattern ^[0-9]{4}$"},
+				{Field: "lines[1].quantity", Code: "TOO_LARGE", Message: "must be at most 99"},
+				{Field: "lines[2].sku", Code: "REQUIRED", Message: "is required"},
+				{Field: "address.postcode", Code: "REQUIRED", Message: "is required"},
+				{Field: "Notes", Code: "TOO_LONG", Message: "must have a length of at most 5"},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := g8.TagValidator{}.Validate(tc.v)
+
+			if tc.expectedErrors == nil {
+				assert.Nil(t, err)
+				return
+			}
+			var errs g8.ValidationErrors
+			assert.True(t, errors.As(err, &errs))
+			assert.Equal(t, tc.expectedErrors, errs)
+		})
+	}
+}
+
+func TestTagValidator_InvalidTag(t *testing.T) {
+	type invalid struct {
+		Name string `json:"name" validate:"max=ten"`
+	}
+
+	err := g8.TagValidator{}.Validate(invalid{Name: "name"})
+
+	assert.EqualError(t, err, "invalid validate tag on field name: invalid bound ten: strconv.ParseFloat: parsing \"ten\": invalid syntax")
+}
+
+func TestAPIGatewayProxyHandler_BindTagValidation(t *testing.T) {
+	h := func(c *g8.APIGatewayProxyContext) error {
+		var o order
+		if err := c.Bind(&o); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, nil)
+	}
+
+	lh := g8.APIGatewayProxyHandler(h, g8.HandlerConfig{
+		Validator: g8.TagValidator{},
+	})
+
+	apitest.New().
+		Handler(adapter.GetHTTPHandlerWithContext(lh, "/", nil, nil)).
+		Post("/").
+		JSON(`{"reference":"ab","status":"pending","total":1,"lines":[{"sku":"1234","quantity":1}]}`).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Body(`{
+					"code": "VALIDATION_ERROR",
+					"detail": "reference: must have a length of at least 3",
+					"fields": [
+						{"field": "reference", "code": "TOO_SHORT", "message": "must have a length of at least 3"}
+					]
+				}`).
+		End()
+}
+
+func TestSQSContext_BindTagValidation(t *testing.T) {
+	h := g8.SQSHandler(func(c *g8.SQSContext) error {
+		var a address
+		return c.Bind(&a)
+	}, g8.HandlerConfig{
+		Logger:    zerolog.New(io.Discard),
+		Validator: g8.TagValidator{},
+	})
+
+	err := h(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{Body: `{"data": {"postcode": ""}, "meta": {"correlation_id": "abcdef"}}`},
+	}})
+
+	var errs g8.ValidationErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, g8.ValidationErrors{{Field: "postcode", Code: "REQUIRED", Message: "is required"}}, errs)
+}
@@ -0,0 +1,242 @@
+package g8
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	newrelic "github.com/newrelic/go-agent"
+	"github.com/newrelic/go-agent/_integrations/nrlambda"
+	"github.com/rotisserie/eris"
+	"github.com/rs/zerolog"
+)
+
+// WebSocket route keys which API Gateway sends for every API
+const (
+	RouteConnect    = "$connect"
+	RouteDisconnect = "$disconnect"
+	RouteDefault    = "$default"
+)
+
+var ErrConnectionGone = Err{
+	Status: http.StatusGone,
+	Code:   "CONNECTION_GONE",
+	Detail: "Connection gone",
+}
+
+// ConnectionSender sends data to WebSocket clients, e.g. using the API Gateway Management API PostToConnection
+// operation. Implementations should return ErrConnectionGone when the client has disconnected.
+type ConnectionSender interface {
+	PostToConnection(ctx context.Context, connectionID string, data []byte) error
+}
+
+type WebSocketContext struct {
+	Context       context.Context
+	Request       events.APIGatewayWebsocketProxyRequest
+	Response      events.APIGatewayProxyResponse
+	Logger        zerolog.Logger
+	NewRelicTx    newrelic.Transaction
+	CorrelationID string
+	ConnectionID  string
+	RouteKey      string
+	validator     Validator
+	errorRenderer ErrorRenderer
+	sender        ConnectionSender
+}
+
+type WebSocketHandlerFunc func(c *WebSocketContext) error
+
+// WebSocketRoutes maps route keys, e.g. RouteConnect or "sendMessage", to the funcs handling them. Messages with a
+// route key which isn't registered are handled by the RouteDefault func, while connections are accepted and
+// disconnections ignored when RouteConnect and RouteDisconnect aren't registered.
+type WebSocketRoutes map[string]WebSocketHandlerFunc
+
+func WebSocketHandler(
+	routes WebSocketRoutes,
+	conf HandlerConfig,
+) func(context.Context, events.APIGatewayWebsocketProxyRequest) (any, error) {
+	h := chainMiddleware(routes.dispatch, conf.WebSocketMiddleware)
+	return func(ctx context.Context, r events.APIGatewayWebsocketProxyRequest) (any, error) {
+		c := newWebSocketContext(ctx, r, conf, conf.WebSocketSender)
+		c.Response.Headers[headerCorrelationID] = c.CorrelationID
+		c.Response.Headers[headerBuildVersion] = conf.BuildVersion
+
+		c.AddNewRelicAttribute("functionName", conf.FunctionName)
+		c.AddNewRelicAttribute("route", c.RouteKey)
+		c.AddNewRelicAttribute("connectionID", c.ConnectionID)
+		c.AddNewRelicAttribute("correlationID", c.CorrelationID)
+		c.AddNewRelicAttribute("buildVersion", conf.BuildVersion)
+
+		err := callHandler(c.NewRelicTx, func() error { return h(c) })
+		if err != nil {
+			c.handleError(err)
+		}
+
+		return c.Response, nil
+	}
+}
+
+func WebSocketHandlerWithNewRelic(routes WebSocketRoutes, conf HandlerConfig) lambda.Handler {
+	return nrlambda.Wrap(WebSocketHandler(routes, conf), conf.NewRelicApp)
+}
+
+func newWebSocketContext(
+	ctx context.Context,
+	r events.APIGatewayWebsocketProxyRequest,
+	conf HandlerConfig,
+	sender ConnectionSender,
+) *WebSocketContext {
+	c := &WebSocketContext{
+		Context:       ctx,
+		Request:       r,
+		Response:      events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Headers: make(map[string]string)},
+		NewRelicTx:    newrelic.FromContext(ctx),
+		ConnectionID:  r.RequestContext.ConnectionID,
+		RouteKey:      r.RequestContext.RouteKey,
+		validator:     conf.Validator,
+		errorRenderer: conf.ErrorRenderer,
+		sender:        sender,
+	}
+	c.CorrelationID = c.GetHeader(headerCorrelationID)
+	if c.CorrelationID == "" {
+		c.CorrelationID = uuid.New().String()
+	}
+	c.Context = ContextWithCorrelationID(ctx, c.CorrelationID)
+
+	c.Logger = configureLogger(conf).
+		Str("route", c.RouteKey).
+		Str("connection_id", c.ConnectionID).
+		Str("correlation_id", c.CorrelationID).
+		Logger()
+	return c
+}
+
+func (routes WebSocketRoutes) dispatch(c *WebSocketContext) error {
+	if h, ok := routes[c.RouteKey]; ok {
+		return h(c)
+	}
+	switch c.RouteKey {
+	case RouteConnect, RouteDisconnect:
+		return nil
+	}
+	if h, ok := routes[RouteDefault]; ok {
+		return h(c)
+	}
+	return ErrNotFound
+}
+
+func (c *WebSocketContext) Bind(v interface{}) error {
+	b, err := decodeBody(c.Request.Body, c.Request.IsBase64Encoded)
+	if err != nil {
+		return err
+	}
+
+	return bindJSON(b, v, c.validator)
+}
+
+// JSON writes the route response, which API Gateway sends to the client when the route has a route response
+func (c *WebSocketContext) JSON(statusCode int, body interface{}) error {
+	var b []byte
+	var err error
+	if body != nil {
+		b, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		c.Response.Headers["Content-Type"] = contentTypeJSON
+	}
+	c.Response.StatusCode = statusCode
+	c.Response.Body = string(b)
+	return nil
+}
+
+// Send sends the data to the client of this connection
+func (c *WebSocketContext) Send(data []byte) error {
+	return c.PostToConnection(c.ConnectionID, data)
+}
+
+// SendJSON sends the value marshalled as JSON to the client of this connection
+func (c *WebSocketContext) SendJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Send(b)
+}
+
+// PostToConnection sends the data to the client of any connection, e.g. to broadcast a message
+func (c *WebSocketContext) PostToConnection(connectionID string, data []byte) error {
+	if c.sender == nil {
+		return eris.New("no WebSocketSender configured")
+	}
+	return c.sender.PostToConnection(c.Context, connectionID, data)
+}
+
+// GetHeader retrieves the header value by name in a case insensitive manner. Headers are only sent for the
+// RouteConnect route.
+func (c *WebSocketContext) GetHeader(name string) string {
+	if v := http.Header(canonicalHeaders(c.Request.MultiValueHeaders)).Get(name); v != "" {
+		return v
+	}
+	return lookupHeader(c.Request.Headers, name)
+}
+
+func (c *WebSocketContext) AddNewRelicAttribute(key string, val interface{}) {
+	if c.NewRelicTx == nil {
+		return
+	}
+	if err := c.NewRelicTx.AddAttribute(key, val); err != nil {
+		c.Logger.Error().Msgf("failed to add attr '%s' to new relic tx: %+v", key, err)
+	}
+}
+
+func (c *WebSocketContext) handleError(err error) {
+	statusCode, contentType, b := renderHandlerError(c.Logger, c.errorRenderer, err, c.CorrelationID)
+	c.Response.Headers["Content-Type"] = contentType
+	c.Response.StatusCode = statusCode
+	c.Response.Body = string(b)
+}
+
+// InMemoryConnectionSender is a ConnectionSender which records the data sent to each connection, e.g. for tests.
+// Connections are open until they are closed with Close, after which ErrConnectionGone is returned.
+type InMemoryConnectionSender struct {
+	mu       sync.Mutex
+	messages map[string][][]byte
+	closed   map[string]bool
+}
+
+func NewInMemoryConnectionSender() *InMemoryConnectionSender {
+	return &InMemoryConnectionSender{
+		messages: make(map[string][][]byte),
+		closed:   make(map[string]bool),
+	}
+}
+
+func (s *InMemoryConnectionSender) PostToConnection(_ context.Context, connectionID string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed[connectionID] {
+		return ErrConnectionGone
+	}
+	s.messages[connectionID] = append(s.messages[connectionID], data)
+	return nil
+}
+
+// Messages returns the data sent to the connection in the order it was sent
+func (s *InMemoryConnectionSender) Messages(connectionID string) [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte(nil), s.messages[connectionID]...)
+}
+
+// Close closes the connection so that sending to it returns ErrConnectionGone
+func (s *InMemoryConnectionSender) Close(connectionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed[connectionID] = true
+}
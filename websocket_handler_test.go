@@ -0,0 +1,240 @@
+package g8_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/JSainsburyPLC/g8"
+)
+
+type trackOrder struct {
+	OrderID string `json:"orderId"`
+}
+
+func TestWebSocketHandler_Dispatch(t *testing.T) {
+	errUnauthorised := g8.Err{Status: http.StatusUnauthorized, Code: "UNAUTHORISED", Detail: "Unauthorised"}
+
+	testCases := map[string]struct {
+		routes         g8.WebSocketRoutes
+		routeKey       string
+		expectedStatus int
+		expectedBody   string
+		expectedCalled string
+	}{
+		"connect": {
+			routes: g8.WebSocketRoutes{
+				g8.RouteConnect: func(c *g8.WebSocketContext) error { return nil },
+			},
+			routeKey:       g8.RouteConnect,
+			expectedStatus: http.StatusOK,
+			expectedCalled: g8.RouteConnect,
+		},
+		"connect rejected": {
+			routes: g8.WebSocketRoutes{
+				g8.RouteConnect: func(c *g8.WebSocketContext) error { return errUnauthorised },
+			},
+			routeKey:       g8.RouteConnect,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"code":"UNAUTHORISED","detail":"Unauthorised"}`,
+			expectedCalled: g8.RouteConnect,
+		},
+		"connect not registered": {
+			routes:         g8.WebSocketRoutes{},
+			routeKey:       g8.RouteConnect,
+			expectedStatus: http.StatusOK,
+		},
+		"disconnect not registered": {
+			routes: g8.WebSocketRoutes{
+				g8.RouteDefault: func(c *g8.WebSocketContext) error { return nil },
+			},
+			routeKey:       g8.RouteDisconnect,
+			expectedStatus: http.StatusOK,
+		},
+		"custom route": {
+			routes: g8.WebSocketRoutes{
+				"trackOrder": func(c *g8.WebSocketContext) error {
+					var o trackOrder
+					if err := c.Bind(&o); err != nil {
+						return err
+					}
+					return c.JSON(http.StatusOK, o)
+				},
+				g8.RouteDefault: func(c *g8.WebSocketContext) error { return nil },
+			},
+			routeKey:       "trackOrder",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"orderId":"order-1"}`,
+			expectedCalled: "trackOrder",
+		},
+		"default route": {
+			routes: g8.WebSocketRoutes{
+				g8.RouteDefault: func(c *g8.WebSocketContext) error { return nil },
+			},
+			routeKey:       "unknown",
+			expectedStatus: http.StatusOK,
+			expectedCalled: g8.RouteDefault,
+		},
+		"route not found": {
+			routes:         g8.WebSocketRoutes{},
+			routeKey:       "unknown",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"code":"NOT_FOUND","detail":"Not found"}`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var called string
+			routes := g8.WebSocketRoutes{}
+			for k, h := range tc.routes {
+				k, h := k, h
+				routes[k] = func(c *g8.WebSocketContext) error {
+					called = k
+					return h(c)
+				}
+			}
+
+			lh := g8.WebSocketHandler(routes, g8.HandlerConfig{})
+
+			res, err := lh(context.Background(), events.APIGatewayWebsocketProxyRequest{
+				Body: `{"action":"trackOrder","orderId":"order-1"}`,
+				RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+					ConnectionID: "conn-1",
+					RouteKey:     tc.routeKey,
+				},
+			})
+
+			assert.Nil(t, err)
+			r := res.(events.APIGatewayProxyResponse)
+			assert.Equal(t, tc.expectedStatus, r.StatusCode)
+			if tc.expectedBody == "" {
+				assert.Empty(t, r.Body)
+			} else {
+				assert.JSONEq(t, tc.expectedBody, r.Body)
+			}
+			assert.Equal(t, tc.expectedCalled, called)
+		})
+	}
+}
+
+func TestWebSocketHandler_Send(t *testing.T) {
+	sender := g8.NewInMemoryConnectionSender()
+	sender.Close("conn-gone")
+
+	var middlewareCalled bool
+	routes := g8.WebSocketRoutes{
+		"trackOrder": func(c *g8.WebSocketContext) error {
+			assert.Equal(t, "conn-1", c.ConnectionID)
+			assert.Equal(t, "trackOrder", c.RouteKey)
+			if err := c.SendJSON(map[string]string{"status": "dispatched"}); err != nil {
+				return err
+			}
+			if err := c.PostToConnection("conn-2", []byte("hello")); err != nil {
+				return err
+			}
+			return c.PostToConnection("conn-gone", []byte("hello"))
+		},
+	}
+
+	lh := g8.WebSocketHandler(routes, g8.HandlerConfig{
+		WebSocketSender: sender,
+		WebSocketMiddleware: []g8.WebSocketMiddleware{
+			func(next g8.WebSocketHandlerFunc) g8.WebSocketHandlerFunc {
+				return func(c *g8.WebSocketContext) error {
+					middlewareCalled = true
+					return next(c)
+				}
+			},
+		},
+	})
+
+	res, err := lh(context.Background(), events.APIGatewayWebsocketProxyRequest{
+		RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+			ConnectionID: "conn-1",
+			RouteKey:     "trackOrder",
+		},
+	})
+
+	assert.Nil(t, err)
+	r := res.(events.APIGatewayProxyResponse)
+	assert.Equal(t, http.StatusGone, r.StatusCode)
+	assert.True(t, middlewareCalled)
+	assert.Equal(t, [][]byte{[]byte(`{"status":"dispatched"}`)}, sender.Messages("conn-1"))
+	assert.Equal(t, [][]byte{[]byte("hello")}, sender.Messages("conn-2"))
+	assert.Empty(t, sender.Messages("conn-gone"))
+}
+
+func TestWebSocketContext_SendWithoutSender(t *testing.T) {
+	c := &g8.WebSocketContext{ConnectionID: "conn-1"}
+
+	err := c.Send([]byte("hello"))
+
+	assert.EqualError(t, err, "no WebSocketSender configured")
+}
+
+func TestLambdaAdapter_WebSocket(t *testing.T) {
+	disconnected := make(chan string, 1)
+	l := g8.LambdaHandler{
+		Handler: g8.WebSocketRoutes{
+			g8.RouteConnect: func(c *g8.WebSocketContext) error {
+				if c.Request.QueryStringParameters["token"] != "secret" {
+					return g8.Err{Status: http.StatusUnauthorized, Code: "UNAUTHORISED", Detail: "Unauthorised"}
+				}
+				return nil
+			},
+			g8.RouteDisconnect: func(c *g8.WebSocketContext) error {
+				disconnected <- c.ConnectionID
+				return nil
+			},
+			"trackOrder": func(c *g8.WebSocketContext) error {
+				var o trackOrder
+				if err := c.Bind(&o); err != nil {
+					return err
+				}
+				return c.SendJSON(map[string]string{"orderId": o.OrderID, "status": "dispatched"})
+			},
+			g8.RouteDefault: func(c *g8.WebSocketContext) error {
+				return c.JSON(http.StatusOK, map[string]string{"error": "unknown action"})
+			},
+		},
+		Method:      http.MethodGet,
+		PathPattern: "/ws",
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(g8.LambdaAdapter(l)))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	t.Run("rejected", func(t *testing.T) {
+		_, res, err := websocket.DefaultDialer.Dial(url, nil)
+		require.True(t, errors.Is(err, websocket.ErrBadHandshake))
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
+	t.Run("messages", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial(url+"?token=secret", nil)
+		require.NoError(t, err)
+
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"action":"trackOrder","orderId":"order-1"}`)))
+		_, msg, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"orderId":"order-1","status":"dispatched"}`, string(msg))
+
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"action":"cancelOrder"}`)))
+		_, msg, err = conn.ReadMessage()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error":"unknown action"}`, string(msg))
+
+		require.NoError(t, conn.Close())
+		assert.NotEmpty(t, <-disconnected)
+	})
+}